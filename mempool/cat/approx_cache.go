@@ -0,0 +1,125 @@
+package cat
+
+import (
+	"encoding/binary"
+	"math"
+
+	tmsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/types"
+)
+
+// ApproxTxCache is a thread-safe, approximate set of transaction keys backed by
+// a counting Bloom filter. It uses a fraction of the memory of an LRUTxCache
+// holding the same number of keys, at the cost of exactness:
+//
+//   - Has never reports a pushed key as absent, but reports a key that was
+//     never pushed as present with probability up to the configured false
+//     positive rate. For dedup this means a new transaction is occasionally
+//     mistaken for one already processed and dropped. Since it is gossiped by
+//     many peers it will usually still reach the mempool through another node.
+//   - The false positive rate holds while at most capacity keys are held.
+//     Unlike LRUTxCache nothing is evicted, so callers must Remove or Reset to
+//     stay within it.
+//   - Removing a key that was never pushed can make other keys appear absent,
+//     and so be re-admitted. Remove therefore only decrements the filter for
+//     keys that test as present, which limits re-admission to the rare case of
+//     removing a false positive.
+type ApproxTxCache struct {
+	mtx tmsync.Mutex
+	// counters holds one saturating counter per filter position. A saturated
+	// counter is never decremented.
+	counters []uint8
+	// hashes is the number of filter positions per key
+	hashes uint64
+}
+
+// NewApproxTxCache returns a cache sized to hold capacity keys with the given
+// false positive rate, which must be in (0, 1). It returns ErrInvalidSize
+// otherwise.
+func NewApproxTxCache(capacity int, falsePositiveRate float64) (*ApproxTxCache, error) {
+	if capacity < 1 || !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		return nil, ErrInvalidSize
+	}
+	// the optimal number of counters and hashes for a Bloom filter
+	size := math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Round(size / float64(capacity) * math.Ln2)
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &ApproxTxCache{
+		counters: make([]uint8, int(size)),
+		hashes:   uint64(hashes),
+	}, nil
+}
+
+// Push adds the key to the cache. It returns false if the key already tested
+// as present, in which case the cache is left unchanged.
+func (c *ApproxTxCache) Push(txKey types.TxKey) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.has(txKey) {
+		return false
+	}
+	c.forEachPosition(txKey, func(i uint64) {
+		if c.counters[i] < math.MaxUint8 {
+			c.counters[i]++
+		}
+	})
+	return true
+}
+
+// Has reports whether the key is probably in the cache.
+func (c *ApproxTxCache) Has(txKey types.TxKey) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.has(txKey)
+}
+
+// Remove removes the key from the cache if it tests as present.
+func (c *ApproxTxCache) Remove(txKey types.TxKey) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.has(txKey) {
+		return
+	}
+	c.forEachPosition(txKey, func(i uint64) {
+		if c.counters[i] < math.MaxUint8 {
+			c.counters[i]--
+		}
+	})
+}
+
+// Reset removes all keys from the cache.
+func (c *ApproxTxCache) Reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i := range c.counters {
+		c.counters[i] = 0
+	}
+}
+
+// has reports whether every filter position of the key is set.
+// This assumes that the cache's mutex is already locked.
+func (c *ApproxTxCache) has(txKey types.TxKey) bool {
+	present := true
+	c.forEachPosition(txKey, func(i uint64) {
+		if c.counters[i] == 0 {
+			present = false
+		}
+	})
+	return present
+}
+
+// forEachPosition calls f with each filter position of the key. Tx keys are
+// hashes already, so the positions are derived from two halves of the key by
+// double hashing.
+func (c *ApproxTxCache) forEachPosition(txKey types.TxKey, f func(i uint64)) {
+	var (
+		size = uint64(len(c.counters))
+		h1   = binary.BigEndian.Uint64(txKey[:8])
+		h2   = binary.BigEndian.Uint64(txKey[8:16]) | 1
+	)
+	for i := uint64(0); i < c.hashes; i++ {
+		f((h1 + i*h2) % size)
+	}
+}
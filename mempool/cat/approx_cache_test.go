@@ -0,0 +1,62 @@
+package cat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApproxTxCache(t *testing.T) {
+	_, err := NewApproxTxCache(0, 0.01)
+	require.ErrorIs(t, err, ErrInvalidSize)
+	_, err = NewApproxTxCache(100, 1)
+	require.ErrorIs(t, err, ErrInvalidSize)
+
+	keys := testTxKeys(3)
+	cache, err := NewApproxTxCache(100, 0.01)
+	require.NoError(t, err)
+	require.True(t, cache.Push(keys[0]))
+	require.False(t, cache.Push(keys[0]))
+	require.True(t, cache.Push(keys[1]))
+	require.True(t, cache.Has(keys[0]))
+	require.True(t, cache.Has(keys[1]))
+	require.False(t, cache.Has(keys[2]))
+
+	// removing a key that was never pushed leaves the others present
+	cache.Remove(keys[2])
+	require.True(t, cache.Has(keys[0]))
+	cache.Remove(keys[0])
+	require.False(t, cache.Has(keys[0]))
+	require.True(t, cache.Has(keys[1]))
+
+	cache.Reset()
+	require.False(t, cache.Has(keys[1]))
+}
+
+func TestApproxTxCacheFalsePositiveRate(t *testing.T) {
+	const (
+		capacity = 10000
+		target   = 0.01
+		probes   = 100000
+	)
+	keys := testTxKeys(capacity + probes)
+	cache, err := NewApproxTxCache(capacity, target)
+	require.NoError(t, err)
+	for _, key := range keys[:capacity] {
+		cache.Push(key)
+	}
+
+	// there are no false negatives
+	for _, key := range keys[:capacity] {
+		require.True(t, cache.Has(key))
+	}
+	falsePositives := 0
+	for _, key := range keys[capacity:] {
+		if cache.Has(key) {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / probes
+	t.Logf("false positive rate %.4f, target %.4f", rate, target)
+	require.Less(t, rate, 1.5*target)
+}
@@ -1,41 +1,406 @@
 package cat
 
 import (
+	"bytes"
 	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
 	"time"
 
+	"github.com/cometbft/cometbft/libs/log"
+	cmtrand "github.com/cometbft/cometbft/libs/rand"
 	tmsync "github.com/cometbft/cometbft/libs/sync"
+	protomem "github.com/cometbft/cometbft/proto/tendermint/mempool"
 	"github.com/cometbft/cometbft/types"
 )
 
+var (
+	ErrCacheFull   = errors.New("cache is full")
+	ErrInvalidSize = errors.New("cache size must not be negative")
+	ErrDisabled    = errors.New("cache is disabled")
+)
+
+// lruTxCacheIDs numbers LRUTxCaches so that locks on several of them are
+// always taken in the same order. See Diff.
+var lruTxCacheIDs atomic.Uint64
+
+const (
+	// minRecommendedCacheSize matches the cache size used by the test config
+	minRecommendedCacheSize = 1000
+	// maxRecommendedCacheSize bounds the recommendation to a few hundred MB of keys
+	maxRecommendedCacheSize = 1 << 24
+)
+
+// RecommendedCacheSize returns the LRUTxCache size needed to retain the keys of
+// all transactions received over the window at the given rate. The result is
+// rounded up and clamped to [minRecommendedCacheSize, maxRecommendedCacheSize].
+func RecommendedCacheSize(txPerSecond float64, window time.Duration) int {
+	size := math.Ceil(txPerSecond * window.Seconds())
+	switch {
+	case math.IsNaN(size) || size < minRecommendedCacheSize:
+		return minRecommendedCacheSize
+	case size > maxRecommendedCacheSize:
+		return maxRecommendedCacheSize
+	default:
+		return int(size)
+	}
+}
+
+// TxCacheBackend stores the keys of transactions the mempool has already
+// processed so that they are not processed again. LRUTxCache is the default,
+// in-memory, implementation. Implementations backed by an external store must
+// be safe for concurrent use.
+type TxCacheBackend interface {
+	// Has reports whether the key is stored
+	Has(txKey types.TxKey) bool
+	// Push stores the key, returning false if it was already stored
+	Push(txKey types.TxKey) bool
+	// Remove deletes the key
+	Remove(txKey types.TxKey)
+	// Reset deletes all keys
+	Reset()
+}
+
+var _ TxCacheBackend = (*LRUTxCache)(nil)
+
 // LRUTxCache maintains a thread-safe LRU cache of raw transactions. The cache
 // only stores the hash of the raw transaction.
 // NOTE: This has been copied from mempool/cache with the main diffence of using
 // tx keys instead of raw transactions.
 type LRUTxCache struct {
+	// id orders the locking of several caches
+	id         uint64
 	staticSize int
 
-	mtx tmsync.Mutex
+	// mtx is read locked by methods that only read the cache, notably Has
+	// when neither the operation log nor hot key detection is enabled
+	mtx tmsync.RWMutex
 	// cacheMap is used as a quick look up table
 	cacheMap map[types.TxKey]*list.Element
 	// list is a doubly linked list used to capture the FIFO nature of the cache
 	list *list.List
+	// logger optionally traces cache decisions. A nil logger disables logging.
+	logger log.Logger
+	// memoryPressure halves the capacity of the cache while set
+	memoryPressure bool
+	// pushSeq is incremented on every push and stamped onto the pushed entry
+	pushSeq uint64
+	// pushTTL is the number of pushes after which an entry expires. 0 disables
+	// push based expiry.
+	pushTTL uint64
+	// pushCountThreshold enables counting repeated pushes of cached keys when
+	// non zero. onPushThreshold is called once a key's count reaches it.
+	pushCountThreshold int
+	onPushThreshold    func(txKey types.TxKey, count int)
+	// clearInPlace makes Reset delete keys from the existing map rather than
+	// allocating a new one
+	clearInPlace bool
+	// highWaterMark is the largest length reached since construction or the
+	// last Reset
+	highWaterMark int
+	// evictions counts the evictions in each of the most recent seconds
+	evictions [evictionBuckets]evictionBucket
+	// now returns the current time. It can be replaced in tests.
+	now func() time.Time
+	// maxSenderFraction enables the sender diversity policy when non zero.
+	// senderCounts tracks the number of cached keys for each sender.
+	maxSenderFraction float64
+	senderCounts      map[string]int
+	// adaptive resizes the cache based on its hit rate when set
+	adaptive *adaptiveSizing
+	// ops optionally records the most recent operations for debugging
+	ops *opLog
+	// minRetention protects keys from eviction until they have been cached
+	// for at least this long. 0 disables the protection.
+	minRetention time.Duration
+	// canEvict optionally vetoes the eviction of keys
+	canEvict func(txKey types.TxKey) bool
+	// onEvict is optionally called with every evicted key
+	onEvict func(txKey types.TxKey)
+	// unsynchronized disables locking of mtx. See NewUnsynchronizedLRUTxCache.
+	unsynchronized bool
+	// hot optionally detects keys that are operated on unusually often
+	hot *hotKeys
+	// overflow optionally keeps evicted keys on disk
+	overflow *DiskOverflow
+	// warmupTarget is the number of keys the cache must hold to be considered
+	// warm. See Seed.
+	warmupTarget int
+	// evictionPaused lets the cache grow past its capacity. See PauseEviction.
+	evictionPaused bool
+	// hits, misses, insertCount and evictionCount count the results of Has,
+	// the admitted keys and the evictions since construction or the last
+	// ResetStats
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	insertCount   atomic.Uint64
+	evictionCount atomic.Uint64
+}
+
+// LRUTxCacheStats are the counters of an LRUTxCache. See LRUTxCache.Stats.
+type LRUTxCacheStats struct {
+	// Hits and Misses count the calls to Has that found and did not find the
+	// key
+	Hits   uint64
+	Misses uint64
+	// Inserts counts the keys admitted to the cache
+	Inserts uint64
+	// Evictions counts the keys evicted to make room for others
+	Evictions uint64
+}
+
+// hotKeys counts the Push and Has calls for each key over fixed windows. A key
+// whose count reaches threshold within a window is added to keys, which holds
+// at most maxHot keys, dropping the oldest. To stay bounded, at most maxTracked
+// keys are counted per window.
+type hotKeys struct {
+	threshold   int
+	window      time.Duration
+	maxHot      int
+	maxTracked  int
+	windowStart time.Time
+	counts      map[types.TxKey]int
+	keys        []types.TxKey
+}
+
+// adaptiveSizing holds the state of the LRUTxCache's hit rate based resizing.
+// Calls to Push with an already cached key count as hits and with a new key as
+// misses. Every interval such calls the hit rate is evaluated: the cache doubles,
+// up to maxSize, if it is below targetHitRate and halves, down to minSize, if
+// it is above the midpoint between targetHitRate and 1. With hysteresis, a
+// resize only happens once sustain consecutive evaluations call for it, and
+// the cooldown evaluations following a resize are skipped.
+type adaptiveSizing struct {
+	size          int
+	minSize       int
+	maxSize       int
+	targetHitRate float64
+	interval      int
+	hits          int
+	misses        int
+	sustain       int
+	cooldown      int
+	// direction is the resize the last streak evaluations called for: 1 to
+	// grow, -1 to shrink
+	direction int
+	streak    int
+	// cooling is the number of evaluations left to skip
+	cooling int
+}
+
+// evictionBuckets is the number of seconds of eviction history kept by the
+// LRUTxCache, bounding the window over which EvictionRate can be computed.
+const evictionBuckets = 60
+
+// evictionBucket counts the evictions that happened within one second
+type evictionBucket struct {
+	second int64
+	count  int
+}
+
+// lruTxEntry is the value of each element in the LRUTxCache's list
+type lruTxEntry struct {
+	key types.TxKey
+	// seq is the push sequence number of the last push of the key
+	seq uint64
+	// pushes counts how many times the key was pushed again while cached
+	pushes int
+	// local marks keys of transactions submitted to this node. They are only
+	// evicted once no other keys remain.
+	local bool
+	// sender optionally records who submitted the transaction
+	sender string
+	// added is when the key was admitted. It is only set while a minimum
+	// retention is configured.
+	added time.Time
 }
 
 func NewLRUTxCache(cacheSize int) *LRUTxCache {
 	return &LRUTxCache{
+		id:         lruTxCacheIDs.Add(1),
 		staticSize: cacheSize,
 		cacheMap:   make(map[types.TxKey]*list.Element, cacheSize),
 		list:       list.New(),
+		now:        time.Now,
+	}
+}
+
+// NewLRUTxCacheChecked is like NewLRUTxCache but returns ErrInvalidSize if the
+// size is negative.
+func NewLRUTxCacheChecked(cacheSize int) (*LRUTxCache, error) {
+	if cacheSize < 0 {
+		return nil, ErrInvalidSize
+	}
+	return NewLRUTxCache(cacheSize), nil
+}
+
+// NewUnsynchronizedLRUTxCache is like NewLRUTxCache but the returned cache does
+// no locking, avoiding the mutex overhead on every operation.
+//
+// WARNING: the cache is NOT safe for concurrent use. It must only be accessed
+// from a single goroutine at a time, e.g. one dedicated goroutine, or with
+// callers providing their own synchronization. Concurrent use corrupts the
+// cache.
+func NewUnsynchronizedLRUTxCache(cacheSize int) *LRUTxCache {
+	c := NewLRUTxCache(cacheSize)
+	c.unsynchronized = true
+	return c
+}
+
+// lock locks the cache's mutex unless the cache is unsynchronized.
+func (c *LRUTxCache) lock() {
+	if !c.unsynchronized {
+		c.mtx.Lock()
+	}
+}
+
+// unlock unlocks the cache's mutex unless the cache is unsynchronized.
+func (c *LRUTxCache) unlock() {
+	if !c.unsynchronized {
+		c.mtx.Unlock()
+	}
+}
+
+// rlock read locks the cache's mutex unless the cache is unsynchronized.
+func (c *LRUTxCache) rlock() {
+	if !c.unsynchronized {
+		c.mtx.RLock()
+	}
+}
+
+// runlock read unlocks the cache's mutex unless the cache is unsynchronized.
+func (c *LRUTxCache) runlock() {
+	if !c.unsynchronized {
+		c.mtx.RUnlock()
+	}
+}
+
+// SetLogger sets a logger to trace cache decisions at debug level. Passing nil
+// disables logging.
+func (c *LRUTxCache) SetLogger(logger log.Logger) {
+	c.lock()
+	defer c.unlock()
+	c.logger = logger
+}
+
+// SetMemoryPressure signals whether the process is under memory pressure.
+// While under pressure the cache operates at half its capacity: it stops
+// growing and each new key evicts old keys until the cache is back within the
+// reduced capacity.
+func (c *LRUTxCache) SetMemoryPressure(underPressure bool) {
+	c.lock()
+	defer c.unlock()
+	c.memoryPressure = underPressure
+}
+
+// SetPushTTL enables logical expiry by push count: an entry that has not been
+// pushed again within the last n pushes to the cache is treated as expired the
+// next time it is accessed. Unlike a time based TTL this is deterministic and
+// independent of the clock. Setting n to 0 disables push based expiry.
+func (c *LRUTxCache) SetPushTTL(n uint64) {
+	c.lock()
+	defer c.unlock()
+	c.pushTTL = n
+}
+
+// EnablePushCounts starts counting how many times each cached key is pushed
+// again, which is a useful signal for resubmission abuse. When a key has been
+// re-pushed threshold times, onThreshold (if non nil) is called with the key
+// and its count outside of the cache's lock. A threshold of 0 disables
+// counting.
+func (c *LRUTxCache) EnablePushCounts(threshold int, onThreshold func(txKey types.TxKey, count int)) {
+	c.lock()
+	defer c.unlock()
+	c.pushCountThreshold = threshold
+	c.onPushThreshold = onThreshold
+}
+
+// PushCount returns how many times the key was pushed again while cached. It
+// is always 0 unless push counting is enabled.
+func (c *LRUTxCache) PushCount(txKey types.TxKey) int {
+	c.lock()
+	defer c.unlock()
+	e, ok := c.lookup(txKey)
+	if !ok {
+		return 0
 	}
+	return e.Value.(*lruTxEntry).pushes
+}
+
+// SetClearInPlace configures Reset to empty the existing map instead of
+// allocating a new one. This reduces GC churn when the cache is reset
+// frequently, at the cost of the map never shrinking back after a burst.
+func (c *LRUTxCache) SetClearInPlace(clearInPlace bool) {
+	c.lock()
+	defer c.unlock()
+	c.clearInPlace = clearInPlace
 }
 
 func (c *LRUTxCache) Reset() {
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	c.lock()
+	defer c.unlock()
+	c.reset()
+}
 
-	c.cacheMap = make(map[types.TxKey]*list.Element, c.staticSize)
+// reset empties the cache.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) reset() {
+	if c.clearInPlace {
+		// the compiler turns this loop into a single map clear
+		for txKey := range c.cacheMap {
+			delete(c.cacheMap, txKey)
+		}
+	} else {
+		c.cacheMap = make(map[types.TxKey]*list.Element, c.staticSize)
+	}
 	c.list.Init()
+	c.highWaterMark = 0
+	c.senderCounts = nil
+	if c.overflow != nil {
+		if err := c.overflow.Reset(); err != nil && c.logger != nil {
+			c.logger.Error("failed to reset overflow", "err", err)
+		}
+	}
+}
+
+// Seed pushes the keys, oldest first, e.g. those of the transactions in recent
+// blocks so that dedup is effective straight after a restart, and sets the
+// number of keys the cache must hold to be considered warm. The target is
+// capped at the cache's size. See WarmupProgress.
+func (c *LRUTxCache) Seed(keys []types.TxKey, target int) {
+	c.lock()
+	if target > c.capacity() {
+		target = c.capacity()
+	}
+	c.warmupTarget = target
+	c.unlock()
+	for _, txKey := range keys {
+		c.Push(txKey)
+	}
+}
+
+// WarmupProgress returns how far, between 0 and 1, the cache has filled up
+// towards the warmup target set by Seed, so that readiness checks can wait for
+// dedup to be trustworthy. It returns 1 if no target was set.
+func (c *LRUTxCache) WarmupProgress() float64 {
+	c.rlock()
+	defer c.runlock()
+	if c.warmupTarget <= 0 || c.list.Len() >= c.warmupTarget {
+		return 1
+	}
+	return float64(c.list.Len()) / float64(c.warmupTarget)
+}
+
+// HighWaterMark returns the largest number of keys held at once since the
+// cache was constructed or last reset.
+func (c *LRUTxCache) HighWaterMark() int {
+	c.rlock()
+	defer c.runlock()
+	return c.highWaterMark
 }
 
 func (c *LRUTxCache) Push(txKey types.TxKey) bool {
@@ -43,170 +408,2157 @@ func (c *LRUTxCache) Push(txKey types.TxKey) bool {
 		return true
 	}
 
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	added, count, onThreshold := c.push(txKey)
+	if onThreshold != nil {
+		onThreshold(txKey, count)
+	}
+	return added
+}
+
+// push adds the key to the cache. If counting repeated pushes, it also returns
+// the key's push count and the callback to invoke if the threshold was reached.
+func (c *LRUTxCache) push(txKey types.TxKey) (bool, int, func(types.TxKey, int)) {
+	c.lock()
+	defer c.unlock()
 
-	moved, ok := c.cacheMap[txKey]
+	moved, ok := c.lookup(txKey)
+	spilled := !ok && c.inOverflow(txKey)
+	c.recordPush(ok || spilled)
+	c.countOp(txKey)
+	c.ops.record("push", txKey, !ok && !spilled)
+	if spilled {
+		c.promote(txKey)
+		return false, 0, nil
+	}
 	if ok {
-		c.list.MoveToBack(moved)
+		c.moveToBack(moved)
+		if c.logger != nil {
+			c.logger.Debug("tx key already in cache", "txKey", txKey)
+		}
+		if c.pushCountThreshold > 0 {
+			entry := moved.Value.(*lruTxEntry)
+			entry.pushes++
+			if entry.pushes == c.pushCountThreshold {
+				return false, entry.pushes, c.onPushThreshold
+			}
+		}
+		return false, 0, nil
+	}
+
+	return c.insert(txKey) != nil, 0, nil
+}
+
+// CompareAndPush atomically checks whether the key is cached and, only if it is
+// absent, pushes it. Unlike Push, an existing key is not moved to the back of
+// the cache. It returns whether the key was inserted.
+func (c *LRUTxCache) CompareAndPush(txKey types.TxKey) bool {
+	if c.staticSize == 0 {
+		return true
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	if _, ok := c.lookup(txKey); ok || c.inOverflow(txKey) {
 		return false
 	}
 
-	if c.list.Len() >= c.staticSize {
-		front := c.list.Front()
-		if front != nil {
-			frontKey := front.Value.(types.TxKey)
-			delete(c.cacheMap, frontKey)
-			c.list.Remove(front)
-		}
+	return c.insert(txKey) != nil
+}
+
+// TryPush adds the key to the cache only if there is room for it, never
+// evicting other keys. It returns ErrDisabled if the cache has a size of 0 and
+// ErrCacheFull if the cache is at capacity, which is halved under memory
+// pressure. Pushing a key that is already cached succeeds and moves it to the
+// back of the cache.
+func (c *LRUTxCache) TryPush(txKey types.TxKey) error {
+	if c.staticSize == 0 {
+		return ErrDisabled
 	}
 
-	e := c.list.PushBack(txKey)
-	c.cacheMap[txKey] = e
+	c.lock()
+	defer c.unlock()
 
-	return true
+	if moved, ok := c.lookup(txKey); ok {
+		c.moveToBack(moved)
+		return nil
+	}
+	// promoting a spilled key could evict another, so it is left on disk
+	if c.inOverflow(txKey) {
+		return nil
+	}
+
+	if c.list.Len() >= c.effectiveCapacity() {
+		return ErrCacheFull
+	}
+
+	if c.insert(txKey) == nil {
+		return ErrCacheFull
+	}
+	return nil
 }
 
-func (c *LRUTxCache) Remove(txKey types.TxKey) {
+// PushLocal is like Push but marks the key as belonging to a transaction
+// submitted to this node. Local keys are only evicted once all other keys have
+// been evicted, which protects a node's own transactions from being crowded out
+// by gossip during spam. Pushing a cached key marks it as local.
+func (c *LRUTxCache) PushLocal(txKey types.TxKey) bool {
 	if c.staticSize == 0 {
-		return
+		return true
 	}
 
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	c.lock()
+	defer c.unlock()
 
-	e := c.cacheMap[txKey]
-	delete(c.cacheMap, txKey)
+	if moved, ok := c.lookup(txKey); ok {
+		c.moveToBack(moved)
+		moved.Value.(*lruTxEntry).local = true
+		return false
+	}
+	if c.inOverflow(txKey) {
+		if e := c.promote(txKey); e != nil {
+			e.Value.(*lruTxEntry).local = true
+		}
+		return false
+	}
 
-	if e != nil {
-		c.list.Remove(e)
+	e := c.insert(txKey)
+	if e == nil {
+		return false
 	}
+	e.Value.(*lruTxEntry).local = true
+	return true
 }
 
-func (c *LRUTxCache) Has(txKey types.TxKey) bool {
+// SetMaxSenderFraction enables a sender diversity policy for keys pushed with
+// PushWithSender. No single sender may occupy more than the given fraction of
+// the cache: pushing a new key for a sender at its cap evicts that sender's
+// oldest key, or is rejected if none of the sender's keys may be evicted, e.g.
+// because of a minimum retention or paused eviction. When the cache is full,
+// the oldest key of the sender with the most keys is evicted rather than the
+// oldest key overall. A fraction of 0 restores strict LRU eviction.
+func (c *LRUTxCache) SetMaxSenderFraction(fraction float64) {
+	c.lock()
+	defer c.unlock()
+	c.maxSenderFraction = fraction
+}
+
+// PushWithSender is like Push but records the sender of the transaction so
+// that the sender diversity policy can be applied.
+func (c *LRUTxCache) PushWithSender(txKey types.TxKey, sender string) bool {
 	if c.staticSize == 0 {
+		return true
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	if moved, ok := c.lookup(txKey); ok {
+		c.moveToBack(moved)
+		return false
+	}
+	if c.inOverflow(txKey) {
+		c.promote(txKey)
 		return false
 	}
 
-	c.mtx.Lock()
-	defer c.mtx.Unlock()
+	if c.maxSenderFraction > 0 && sender != "" {
+		limit := int(c.maxSenderFraction * float64(c.capacity()))
+		if limit < 1 {
+			limit = 1
+		}
+		if c.senderCounts[sender] >= limit {
+			var oldest *list.Element
+			if !c.evictionPaused {
+				oldest = c.oldestOfSender(sender)
+			}
+			if oldest == nil {
+				return false
+			}
+			c.recordEviction(c.removeElement(oldest))
+		}
+	}
 
-	_, ok := c.cacheMap[txKey]
-	return ok
+	e := c.insert(txKey)
+	if e == nil {
+		return false
+	}
+	entry := e.Value.(*lruTxEntry)
+	if sender != "" {
+		entry.sender = sender
+		if c.senderCounts == nil {
+			c.senderCounts = make(map[string]int)
+		}
+		c.senderCounts[sender]++
+	}
+	return true
 }
 
-// SeenTxSet records transactions that have been
-// seen by other peers but not yet by us
-type SeenTxSet struct {
-	mtx tmsync.Mutex
-	set map[types.TxKey]timestampedPeerSet
+// oldestOfSender returns the oldest non local element pushed by the sender.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) oldestOfSender(sender string) *list.Element {
+	for e := c.list.Front(); e != nil; e = e.Next() {
+		if entry := e.Value.(*lruTxEntry); entry.sender == sender && !entry.local && c.evictable(entry) {
+			return e
+		}
+	}
+	return nil
 }
 
-type timestampedPeerSet struct {
-	peers map[uint16]struct{}
-	time  time.Time
+// EnableAdaptiveSizing lets the cache resize itself between minSize and maxSize
+// to reach the target hit rate, evaluated every interval calls to Push. The cache
+// starts at its configured size clamped to the bounds. It returns
+// ErrInvalidSize if the bounds or interval are invalid and ErrDisabled if the
+// cache was created with a size of 0.
+func (c *LRUTxCache) EnableAdaptiveSizing(minSize, maxSize int, targetHitRate float64, interval int) error {
+	if c.staticSize == 0 {
+		return ErrDisabled
+	}
+	if minSize < 1 || maxSize < minSize || interval < 1 {
+		return ErrInvalidSize
+	}
+	size := c.staticSize
+	switch {
+	case size < minSize:
+		size = minSize
+	case size > maxSize:
+		size = maxSize
+	}
+
+	c.lock()
+	defer c.unlock()
+	c.adaptive = &adaptiveSizing{
+		size:          size,
+		minSize:       minSize,
+		maxSize:       maxSize,
+		targetHitRate: targetHitRate,
+		interval:      interval,
+		sustain:       1,
+	}
+	c.shrinkTo(size)
+	return nil
 }
 
-func NewSeenTxSet() *SeenTxSet {
-	return &SeenTxSet{
-		set: make(map[types.TxKey]timestampedPeerSet),
+// SetAdaptiveHysteresis stabilizes adaptive sizing under noisy workloads: the
+// cache is only resized once sustain consecutive evaluations call for the same
+// resize, and the cooldown evaluations after a resize are skipped. The defaults
+// of 1 and 0 resize on every evaluation that calls for it. It returns
+// ErrDisabled if adaptive sizing is not enabled and ErrInvalidSize if sustain
+// is below 1 or cooldown is negative.
+func (c *LRUTxCache) SetAdaptiveHysteresis(sustain, cooldown int) error {
+	if sustain < 1 || cooldown < 0 {
+		return ErrInvalidSize
 	}
+	c.lock()
+	defer c.unlock()
+	if c.adaptive == nil {
+		return ErrDisabled
+	}
+	c.adaptive.sustain, c.adaptive.cooldown = sustain, cooldown
+	c.adaptive.direction, c.adaptive.streak, c.adaptive.cooling = 0, 0, 0
+	return nil
 }
 
-func (s *SeenTxSet) Add(txKey types.TxKey, peer uint16) {
-	if peer == 0 {
+// AdaptiveHysteresis returns the hysteresis parameters of adaptive sizing. ok
+// is false if adaptive sizing is not enabled.
+func (c *LRUTxCache) AdaptiveHysteresis() (sustain, cooldown int, ok bool) {
+	c.rlock()
+	defer c.runlock()
+	if c.adaptive == nil {
+		return 0, 0, false
+	}
+	return c.adaptive.sustain, c.adaptive.cooldown, true
+}
+
+// AdaptiveSizing returns the current size, bounds and target hit rate of the
+// cache. ok is false if adaptive sizing is not enabled.
+func (c *LRUTxCache) AdaptiveSizing() (size, minSize, maxSize int, targetHitRate float64, ok bool) {
+	c.rlock()
+	defer c.runlock()
+	if c.adaptive == nil {
+		return 0, 0, 0, 0, false
+	}
+	a := c.adaptive
+	return a.size, a.minSize, a.maxSize, a.targetHitRate, true
+}
+
+// capacity returns the current size of the cache.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) capacity() int {
+	if c.adaptive != nil {
+		return c.adaptive.size
+	}
+	return c.staticSize
+}
+
+// recordPush counts a push towards the hit rate and resizes the cache at the
+// end of each interval. It is a no-op unless adaptive sizing is enabled.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) recordPush(hit bool) {
+	a := c.adaptive
+	if a == nil {
 		return
 	}
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	seenSet, exists := s.set[txKey]
-	if !exists {
-		s.set[txKey] = timestampedPeerSet{
-			peers: map[uint16]struct{}{peer: {}},
-			time:  time.Now().UTC(),
+	if hit {
+		a.hits++
+	} else {
+		a.misses++
+	}
+	if a.hits+a.misses < a.interval {
+		return
+	}
+
+	hitRate := float64(a.hits) / float64(a.hits+a.misses)
+	a.hits, a.misses = 0, 0
+	if a.cooling > 0 {
+		a.cooling--
+		return
+	}
+
+	direction := 0
+	switch {
+	case hitRate < a.targetHitRate && a.size < a.maxSize:
+		direction = 1
+	case hitRate > (a.targetHitRate+1)/2 && a.size > a.minSize:
+		direction = -1
+	}
+	if direction == 0 || direction != a.direction {
+		a.direction, a.streak = direction, 0
+	}
+	if direction == 0 {
+		return
+	}
+	if a.streak++; a.streak < a.sustain {
+		return
+	}
+	a.direction, a.streak, a.cooling = 0, 0, a.cooldown
+
+	if direction > 0 {
+		a.size *= 2
+		if a.size > a.maxSize {
+			a.size = a.maxSize
 		}
 	} else {
-		seenSet.peers[peer] = struct{}{}
+		a.size /= 2
+		if a.size < a.minSize {
+			a.size = a.minSize
+		}
+		c.shrinkTo(a.size)
+	}
+	if c.logger != nil {
+		c.logger.Debug("resized tx cache", "size", a.size, "hitRate", hitRate)
 	}
 }
 
-func (s *SeenTxSet) Pop(txKey types.TxKey) uint16 {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	seenSet, exists := s.set[txKey]
-	if exists {
-		for peer := range seenSet.peers {
-			delete(seenSet.peers, peer)
-			return peer
-		}
+// effectiveCapacity returns the current size of the cache, halved while under
+// memory pressure.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) effectiveCapacity() int {
+	capacity := c.capacity()
+	if c.memoryPressure && capacity > 1 {
+		capacity /= 2
 	}
-	return 0
+	return capacity
 }
 
-func (s *SeenTxSet) RemoveKey(txKey types.TxKey) {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	delete(s.set, txKey)
+// PauseEviction lets the cache grow past its capacity, e.g. during a known
+// transient spike in transactions, until ResumeEviction is called. While paused
+// no keys are evicted to make room for others.
+func (c *LRUTxCache) PauseEviction() {
+	c.lock()
+	defer c.unlock()
+	c.evictionPaused = true
 }
 
-func (s *SeenTxSet) Remove(txKey types.TxKey, peer uint16) {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	set, exists := s.set[txKey]
-	if exists {
-		if len(set.peers) == 1 {
-			delete(s.set, txKey)
-		} else {
-			delete(set.peers, peer)
+// ResumeEviction ends a PauseEviction, evicting keys until the cache is back
+// within its capacity. Keys protected from eviction are kept, so the cache may
+// remain over capacity until they become evictable.
+func (c *LRUTxCache) ResumeEviction() {
+	c.lock()
+	defer c.unlock()
+	c.evictionPaused = false
+	c.shrinkTo(c.effectiveCapacity())
+}
+
+// shrinkTo evicts keys until at most size remain. It is a no-op while eviction
+// is paused.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) shrinkTo(size int) {
+	for !c.evictionPaused && c.list.Len() > size {
+		victim := c.victim()
+		if victim == nil {
+			return
 		}
+		c.recordEviction(c.removeElement(victim))
 	}
 }
 
-func (s *SeenTxSet) Prune(limit time.Time) {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	for key, seenSet := range s.set {
-		if seenSet.time.Before(limit) {
-			delete(s.set, key)
-		}
+// EnableOpLog records the last size operations (push, has, remove and evict)
+// for retrieval with RecentOps. This adds overhead to every operation and is
+// meant for debugging. A size of 0 disables the log.
+func (c *LRUTxCache) EnableOpLog(size int) {
+	c.lock()
+	defer c.unlock()
+	c.ops = nil
+	if size > 0 {
+		c.ops = newOpLog(size)
 	}
 }
 
-func (s *SeenTxSet) Has(txKey types.TxKey, peer uint16) bool {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	seenSet, exists := s.set[txKey]
-	if !exists {
+// RecentOps returns the operations recorded since EnableOpLog, oldest first.
+func (c *LRUTxCache) RecentOps() []CacheOp {
+	c.rlock()
+	defer c.runlock()
+	return c.ops.recent()
+}
+
+// SetMinRetention protects newly admitted keys from eviction for the given
+// duration, so that a burst of new keys cannot immediately push them out. When
+// the cache is full and every key is protected, new keys are rejected. A
+// duration of 0 disables the protection.
+func (c *LRUTxCache) SetMinRetention(d time.Duration) {
+	c.lock()
+	defer c.unlock()
+	c.minRetention = d
+}
+
+// SetCanEvict sets a function consulted before evicting a key. Keys it returns
+// false for are skipped in favour of the next oldest key, and if every key is
+// vetoed new keys are rejected. It is called with the cache locked and must not
+// call back into the cache. Passing nil removes the veto.
+func (c *LRUTxCache) SetCanEvict(canEvict func(txKey types.TxKey) bool) {
+	c.lock()
+	defer c.unlock()
+	c.canEvict = canEvict
+}
+
+// SetOnEvict sets a function called with each key evicted to make room for
+// others. It is called with the cache locked, on the Push path, so it must be
+// fast and must not call back into the cache; for slower work hand the keys to
+// an EvictionQueue. Passing nil removes the callback.
+func (c *LRUTxCache) SetOnEvict(onEvict func(txKey types.TxKey)) {
+	c.lock()
+	defer c.unlock()
+	c.onEvict = onEvict
+}
+
+// evictable reports whether the entry has outlived the minimum retention and is
+// not vetoed by canEvict.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) evictable(entry *lruTxEntry) bool {
+	if c.minRetention > 0 && c.now().Sub(entry.added) < c.minRetention {
 		return false
 	}
-	_, has := seenSet.peers[peer]
-	return has
+	return c.canEvict == nil || c.canEvict(entry.key)
 }
 
-func (s *SeenTxSet) Get(txKey types.TxKey) map[uint16]struct{} {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	seenSet, exists := s.set[txKey]
-	if !exists {
-		return nil
+// SetOverflow spills evicted keys to the given disk backed overflow, so that a
+// spilled key is still reported as stored by Has, Missing and the Push methods.
+// Pushing a spilled key again moves it back into memory, except with TryPush
+// and CompareAndPush, which leave it in place. This extends the dedup window
+// without holding every key in memory. Passing nil disables the overflow; the
+// caller remains responsible for closing it.
+func (c *LRUTxCache) SetOverflow(overflow *DiskOverflow) {
+	c.lock()
+	defer c.unlock()
+	c.overflow = overflow
+}
+
+// EnableHotKeys reports, via HotKeys, keys that are pushed or looked up at least
+// threshold times within a window, e.g. a single tx amplified by gossip. At
+// most maxHot keys are reported. A threshold of 0 disables detection.
+func (c *LRUTxCache) EnableHotKeys(threshold int, window time.Duration, maxHot int) {
+	c.lock()
+	defer c.unlock()
+	if threshold <= 0 || maxHot <= 0 {
+		c.hot = nil
+		return
 	}
-	// make a copy of the struct to avoid concurrency issues
-	peers := make(map[uint16]struct{}, len(seenSet.peers))
-	for peer := range seenSet.peers {
-		peers[peer] = struct{}{}
+	maxTracked := c.staticSize
+	if maxTracked < minRecommendedCacheSize {
+		maxTracked = minRecommendedCacheSize
+	}
+	c.hot = &hotKeys{
+		threshold:  threshold,
+		window:     window,
+		maxHot:     maxHot,
+		maxTracked: maxTracked,
+		counts:     make(map[types.TxKey]int),
 	}
-	return peers
 }
 
-// Len returns the amount of cached items. Mostly used for testing.
-func (s *SeenTxSet) Len() int {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	return len(s.set)
+// HotKeys returns the keys found to be hot since EnableHotKeys, oldest first.
+func (c *LRUTxCache) HotKeys() []types.TxKey {
+	c.rlock()
+	defer c.runlock()
+	if c.hot == nil {
+		return nil
+	}
+	return append([]types.TxKey(nil), c.hot.keys...)
 }
 
-func (s *SeenTxSet) Reset() {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	s.set = make(map[types.TxKey]timestampedPeerSet)
+// countOp counts an operation on the key towards hot key detection. It is a
+// no-op unless hot key detection is enabled.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) countOp(txKey types.TxKey) {
+	h := c.hot
+	if h == nil {
+		return
+	}
+	now := c.now()
+	if now.Sub(h.windowStart) >= h.window {
+		h.windowStart = now
+		h.counts = make(map[types.TxKey]int)
+	}
+	count, tracked := h.counts[txKey]
+	if !tracked && len(h.counts) >= h.maxTracked {
+		return
+	}
+	count++
+	h.counts[txKey] = count
+	if count != h.threshold {
+		return
+	}
+	for i, key := range h.keys {
+		if key == txKey {
+			h.keys = append(h.keys[:i], h.keys[i+1:]...)
+			break
+		}
+	}
+	if len(h.keys) == h.maxHot {
+		h.keys = h.keys[1:]
+	}
+	h.keys = append(h.keys, txKey)
+	if c.logger != nil {
+		c.logger.Debug("detected hot tx key", "txKey", txKey, "ops", count)
+	}
+}
+
+// insert adds a new key to the back of the cache, evicting the oldest key if
+// the cache is full. It returns nil, without adding the key, if the cache is
+// full and no key may be evicted.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) insert(txKey types.TxKey) *list.Element {
+	capacity := c.effectiveCapacity()
+	for !c.evictionPaused && c.list.Len() >= capacity {
+		victim := c.victim()
+		if victim == nil {
+			if c.logger != nil {
+				c.logger.Debug("rejected tx key from full cache", "txKey", txKey)
+			}
+			return nil
+		}
+		victimKey := c.removeElement(victim)
+		c.recordEviction(victimKey)
+		c.ops.record("evict", victimKey, true)
+		if c.logger != nil {
+			c.logger.Debug("evicted tx key from cache", "txKey", victimKey)
+		}
+	}
+
+	c.pushSeq++
+	entry := &lruTxEntry{key: txKey, seq: c.pushSeq}
+	if c.minRetention > 0 {
+		entry.added = c.now()
+	}
+	e := c.list.PushBack(entry)
+	c.cacheMap[txKey] = e
+	c.insertCount.Add(1)
+	if c.list.Len() > c.highWaterMark {
+		c.highWaterMark = c.list.Len()
+	}
+	if c.logger != nil {
+		c.logger.Debug("admitted tx key to cache", "txKey", txKey)
+	}
+	return e
+}
+
+// victim returns the element to evict next: the oldest non local key, or the
+// oldest key if all keys are local. If the sender diversity policy is enabled,
+// the oldest key of the sender with the most keys is preferred. Keys within the
+// minimum retention or vetoed by canEvict are skipped. It returns nil if no key
+// may be evicted.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) victim() *list.Element {
+	if c.maxSenderFraction > 0 {
+		var (
+			dominant string
+			most     int
+		)
+		for sender, count := range c.senderCounts {
+			if count > most {
+				dominant, most = sender, count
+			}
+		}
+		if most > 0 {
+			if e := c.oldestOfSender(dominant); e != nil {
+				return e
+			}
+		}
+	}
+	for e := c.list.Front(); e != nil; e = e.Next() {
+		if entry := e.Value.(*lruTxEntry); !entry.local && c.evictable(entry) {
+			return e
+		}
+	}
+	for e := c.list.Front(); e != nil; e = e.Next() {
+		if c.evictable(e.Value.(*lruTxEntry)) {
+			return e
+		}
+	}
+	return nil
+}
+
+// NextVictim returns the key that the next overflow would evict, taking the
+// local, sender diversity and minimum retention policies into account, without
+// evicting it. ok is false if no key may be evicted.
+func (c *LRUTxCache) NextVictim() (txKey types.TxKey, ok bool) {
+	c.rlock()
+	defer c.runlock()
+	victim := c.victim()
+	if victim == nil {
+		return types.TxKey{}, false
+	}
+	return victim.Value.(*lruTxEntry).key, true
+}
+
+// moveToBack marks the element as the most recently pushed.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) moveToBack(e *list.Element) {
+	c.pushSeq++
+	e.Value.(*lruTxEntry).seq = c.pushSeq
+	c.list.MoveToBack(e)
+}
+
+// lookup returns the element for the key. An entry that has expired by push
+// count is removed and reported as absent.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) lookup(txKey types.TxKey) (*list.Element, bool) {
+	e, ok := c.cacheMap[txKey]
+	if !ok {
+		return nil, false
+	}
+	if c.pushTTL > 0 && c.pushSeq-e.Value.(*lruTxEntry).seq >= c.pushTTL {
+		c.removeElement(e)
+		return nil, false
+	}
+	return e, true
+}
+
+// removeElement deletes the element from the cache and returns its key.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) removeElement(e *list.Element) types.TxKey {
+	entry := c.list.Remove(e).(*lruTxEntry)
+	delete(c.cacheMap, entry.key)
+	if entry.sender != "" {
+		if c.senderCounts[entry.sender]--; c.senderCounts[entry.sender] == 0 {
+			delete(c.senderCounts, entry.sender)
+		}
+	}
+	return entry.key
+}
+
+func (c *LRUTxCache) Remove(txKey types.TxKey) {
+	if c.staticSize == 0 {
+		return
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	e, ok := c.cacheMap[txKey]
+	if ok {
+		c.removeElement(e)
+	}
+	if c.overflow != nil {
+		if err := c.overflow.Remove(txKey); err != nil && c.logger != nil {
+			c.logger.Error("failed to remove tx key from overflow", "txKey", txKey, "err", err)
+		}
+	}
+	c.ops.record("remove", txKey, ok)
+}
+
+// Len returns the amount of cached keys.
+func (c *LRUTxCache) Len() int {
+	c.rlock()
+	defer c.runlock()
+	return c.list.Len()
+}
+
+// EvictOldest removes and returns, oldest first, up to n keys from the front of
+// the cache in a single locked operation, e.g. to relieve memory pressure. The
+// keys are taken strictly in list order: local keys, the minimum retention and
+// the eviction veto are not considered. They are counted as evictions.
+func (c *LRUTxCache) EvictOldest(n int) []types.TxKey {
+	c.lock()
+	defer c.unlock()
+	if n > c.list.Len() {
+		n = c.list.Len()
+	}
+	if n <= 0 {
+		return nil
+	}
+	keys := make([]types.TxKey, 0, n)
+	for len(keys) < n {
+		txKey := c.removeElement(c.list.Front())
+		c.recordEviction(txKey)
+		c.ops.record("evict", txKey, true)
+		keys = append(keys, txKey)
+	}
+	return keys
+}
+
+// evictOldest removes the next eviction victim. It returns false if the cache
+// is empty.
+func (c *LRUTxCache) evictOldest() bool {
+	c.lock()
+	defer c.unlock()
+
+	victim := c.victim()
+	if victim == nil {
+		return false
+	}
+	c.recordEviction(c.removeElement(victim))
+	return true
+}
+
+// recordEviction counts an eviction of the key in the bucket for the current
+// second, spills the key to the overflow, if any, and calls onEvict.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) recordEviction(txKey types.TxKey) {
+	if c.onEvict != nil {
+		c.onEvict(txKey)
+	}
+	if c.overflow != nil {
+		if err := c.overflow.Push(txKey); err != nil && c.logger != nil {
+			c.logger.Error("failed to spill tx key to overflow", "txKey", txKey, "err", err)
+		}
+	}
+	c.evictionCount.Add(1)
+	second := c.now().Unix()
+	bucket := &c.evictions[second%evictionBuckets]
+	if bucket.second != second {
+		*bucket = evictionBucket{second: second}
+	}
+	bucket.count++
+}
+
+// EvictionRate returns the average number of evictions per second over the
+// window, which is capped to the last minute. An adaptive controller can use
+// it to grow the cache when evictions are frequent and shrink it when rare.
+func (c *LRUTxCache) EvictionRate(window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+	if window > evictionBuckets*time.Second {
+		window = evictionBuckets * time.Second
+	}
+
+	c.rlock()
+	defer c.runlock()
+
+	now := c.now().Unix()
+	seconds := int64(math.Ceil(window.Seconds()))
+	total := 0
+	for _, bucket := range c.evictions {
+		if bucket.second > now-seconds && bucket.second <= now {
+			total += bucket.count
+		}
+	}
+	return float64(total) / window.Seconds()
+}
+
+// Has reports whether the key is cached. Unless the operation log or hot key
+// detection is enabled, it only takes the read lock so that lookups do not
+// serialize against each other. Expired keys are then reported absent but
+// left for the next write to remove.
+func (c *LRUTxCache) Has(txKey types.TxKey) bool {
+	if c.staticSize == 0 {
+		return false
+	}
+
+	c.rlock()
+	if c.ops == nil && c.hot == nil {
+		defer c.runlock()
+		ok := c.contains(txKey) || c.inOverflow(txKey)
+		c.countHas(ok)
+		return ok
+	}
+	c.runlock()
+
+	c.lock()
+	defer c.unlock()
+
+	_, ok := c.lookup(txKey)
+	if !ok {
+		ok = c.inOverflow(txKey)
+	}
+	c.countHas(ok)
+	c.ops.record("has", txKey, ok)
+	c.countOp(txKey)
+	return ok
+}
+
+// countHas counts the result of a call to Has.
+func (c *LRUTxCache) countHas(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// Stats returns the cache's counters.
+func (c *LRUTxCache) Stats() LRUTxCacheStats {
+	return LRUTxCacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Inserts:   c.insertCount.Load(),
+		Evictions: c.evictionCount.Load(),
+	}
+}
+
+// Churn returns the ratio of evictions to inserts since construction or the
+// last ResetStats. A churn near 1 means nearly every insert evicts another key,
+// i.e. the cache is too small, while a churn near 0 means it has plenty of
+// headroom. It returns 0 if nothing was inserted.
+func (c *LRUTxCache) Churn() float64 {
+	inserts := c.insertCount.Load()
+	if inserts == 0 {
+		return 0
+	}
+	return float64(c.evictionCount.Load()) / float64(inserts)
+}
+
+// ResetStats zeroes the cache's counters, along with the eviction history used
+// by EvictionRate and the hit rate of the current adaptive sizing interval,
+// leaving the cached keys intact. This allows measuring over a fresh window.
+func (c *LRUTxCache) ResetStats() {
+	c.lock()
+	defer c.unlock()
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.insertCount.Store(0)
+	c.evictionCount.Store(0)
+	c.evictions = [evictionBuckets]evictionBucket{}
+	if c.adaptive != nil {
+		c.adaptive.hits, c.adaptive.misses = 0, 0
+	}
+}
+
+// contains is like lookup but only reads the cache: an expired key is reported
+// absent without being removed.
+// This assumes that the cache's mutex is at least read locked.
+func (c *LRUTxCache) contains(txKey types.TxKey) bool {
+	e, ok := c.cacheMap[txKey]
+	if !ok {
+		return false
+	}
+	return c.pushTTL == 0 || c.pushSeq-e.Value.(*lruTxEntry).seq < c.pushTTL
+}
+
+// holds reports whether the key is cached, in memory or in the overflow,
+// without counting towards Stats, recording an operation or warming a hot key.
+// It is meant for lookups made on behalf of other caches or for diagnostics.
+func (c *LRUTxCache) holds(txKey types.TxKey) bool {
+	if c.staticSize == 0 {
+		return false
+	}
+	c.rlock()
+	defer c.runlock()
+	return c.contains(txKey) || c.inOverflow(txKey)
+}
+
+// promote moves a key spilled to the overflow back into memory as the most
+// recently pushed key, as pushing a cached key again would. It returns the
+// key's element, or nil if there was no room for it, in which case the key is
+// left in the overflow.
+// This assumes that the cache's mutex is already locked.
+func (c *LRUTxCache) promote(txKey types.TxKey) *list.Element {
+	e := c.insert(txKey)
+	if e == nil {
+		return nil
+	}
+	if err := c.overflow.Remove(txKey); err != nil && c.logger != nil {
+		c.logger.Error("failed to remove tx key from overflow", "txKey", txKey, "err", err)
+	}
+	return e
+}
+
+// inOverflow reports whether the key is in the overflow, if any.
+// This assumes that the cache's mutex is at least read locked.
+func (c *LRUTxCache) inOverflow(txKey types.TxKey) bool {
+	if c.overflow == nil {
+		return false
+	}
+	ok, err := c.overflow.Has(txKey)
+	if err != nil && c.logger != nil {
+		c.logger.Error("failed to read tx key from overflow", "txKey", txKey, "err", err)
+	}
+	return ok
+}
+
+// Missing returns the keys that are not currently cached, preserving their
+// order. All keys are checked under a single lock.
+func (c *LRUTxCache) Missing(keys []types.TxKey) []types.TxKey {
+	if c.staticSize == 0 {
+		return keys
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	missing := make([]types.TxKey, 0, len(keys))
+	for _, txKey := range keys {
+		if _, ok := c.lookup(txKey); !ok && !c.inOverflow(txKey) {
+			missing = append(missing, txKey)
+		}
+	}
+	return missing
+}
+
+// CoverageOf reports how many of the given keys, e.g. the txs of recent
+// blocks, are still cached and so would be deduplicated if gossiped again. A
+// low ratio of present to total means the cache is too small for the rate of
+// transactions. It does not affect the cache's stats or recency.
+func (c *LRUTxCache) CoverageOf(hashes []types.TxKey) (present, total int) {
+	if c.staticSize == 0 {
+		return 0, len(hashes)
+	}
+
+	c.rlock()
+	defer c.runlock()
+	for _, txKey := range hashes {
+		if c.contains(txKey) || c.inOverflow(txKey) {
+			present++
+		}
+	}
+	return present, len(hashes)
+}
+
+// Snapshot returns the number of cached keys together with the keys, ordered
+// from least to most recently pushed, both read under the same lock.
+func (c *LRUTxCache) Snapshot() (int, []types.TxKey) {
+	c.rlock()
+	defer c.runlock()
+
+	keys := make([]types.TxKey, 0, c.list.Len())
+	for e := c.list.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*lruTxEntry).key)
+	}
+	return c.list.Len(), keys
+}
+
+// OrderedKeys returns the cached keys strictly in eviction order, from the
+// oldest at the front of the list to the newest at the back.
+func (c *LRUTxCache) OrderedKeys() []types.TxKey {
+	_, keys := c.Snapshot()
+	return keys
+}
+
+// Touch marks the key as the most recently used without pushing it, so it is
+// not counted as a push and its push based expiry is not refreshed. It returns
+// false if the key is not cached.
+func (c *LRUTxCache) Touch(txKey types.TxKey) bool {
+	c.lock()
+	defer c.unlock()
+	e, ok := c.lookup(txKey)
+	if ok {
+		c.list.MoveToBack(e)
+	}
+	return ok
+}
+
+// Diff returns the keys cached by a but not b and those cached by b but not a,
+// each from least to most recently pushed, e.g. to detect and repair drift
+// between a primary and a replica cache. Both caches are read locked for the
+// duration, in a fixed order so that concurrent calls cannot deadlock.
+func Diff(a, b *LRUTxCache) (onlyA, onlyB []types.TxKey) {
+	if a == b {
+		return nil, nil
+	}
+	first, second := a, b
+	if second.id < first.id {
+		first, second = second, first
+	}
+	first.rlock()
+	defer first.runlock()
+	second.rlock()
+	defer second.runlock()
+
+	return a.keysNotIn(b), b.keysNotIn(a)
+}
+
+// keysNotIn returns the keys held in memory by the cache, from least to most
+// recently pushed, that other does not hold, agreeing with Has on both sides:
+// expired keys are left out and keys spilled to other's overflow are held.
+// This assumes that both caches' mutexes are already locked.
+func (c *LRUTxCache) keysNotIn(other *LRUTxCache) []types.TxKey {
+	var keys []types.TxKey
+	for e := c.list.Front(); e != nil; e = e.Next() {
+		txKey := e.Value.(*lruTxEntry).key
+		if !c.contains(txKey) {
+			continue
+		}
+		if !other.contains(txKey) && !other.inOverflow(txKey) {
+			keys = append(keys, txKey)
+		}
+	}
+	return keys
+}
+
+// Split partitions the cached keys into n new caches, choosing the cache for
+// each key with BucketFor. Recency order, as well as the sender and whether
+// each key is local, is preserved within each cache. Each cache's size is its
+// share of this cache's size, grown if necessary so that no key is lost. This
+// supports migrating a single cache to a sharded layout without losing dedup
+// state.
+func (c *LRUTxCache) Split(n int) []*LRUTxCache {
+	if n < 1 {
+		return nil
+	}
+
+	c.lock()
+	defer c.unlock()
+
+	shards := make([][]*lruTxEntry, n)
+	for e := c.list.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*lruTxEntry)
+		i := BucketFor(entry.key, n)
+		shards[i] = append(shards[i], entry)
+	}
+
+	caches := make([]*LRUTxCache, n)
+	for i, entries := range shards {
+		size := (c.staticSize + n - 1) / n
+		if len(entries) > size {
+			size = len(entries)
+		}
+		caches[i] = NewLRUTxCache(size)
+		for _, entry := range entries {
+			copied := caches[i].insert(entry.key).Value.(*lruTxEntry)
+			copied.local, copied.sender = entry.local, entry.sender
+			if entry.sender != "" {
+				if caches[i].senderCounts == nil {
+					caches[i].senderCounts = make(map[string]int)
+				}
+				caches[i].senderCounts[entry.sender]++
+			}
+		}
+	}
+	return caches
+}
+
+// BucketFor maps a key to one of n buckets using jump consistent hashing over
+// the first 8 bytes of the key. When n grows to n+1 only about 1/(n+1) of the
+// keys move, all of them to the new bucket, so shard counts can change without
+// rehashing everything. It panics if n is not positive.
+func BucketFor(txKey types.TxKey, n int) int {
+	if n <= 0 {
+		panic("BucketFor: n must be positive")
+	}
+	var (
+		hash         = binary.BigEndian.Uint64(txKey[:8])
+		bucket int64 = -1
+		next   int64
+	)
+	for next < int64(n) {
+		bucket = next
+		hash = hash*2862933555777941757 + 1
+		next = int64(float64(bucket+1) * (float64(int64(1)<<31) / float64((hash>>33)+1)))
+	}
+	return int(bucket)
+}
+
+// SeenTxSet records transactions that have been
+// seen by other peers but not yet by us
+//
+// The underlying map is allocated lazily on the first Add, using the capacity
+// hint given at construction, and from then on grows as Go maps do by doubling
+// its buckets as entries are added. This avoids a large upfront allocation on
+// nodes that never see much gossip.
+type SeenTxSet struct {
+	// mtx is read locked by methods that only read the set, so that readers
+	// such as Has and Get do not serialize against each other
+	mtx tmsync.RWMutex
+	set map[types.TxKey]*timestampedPeerSet
+	// capacityHint is the initial size of the set when it is first allocated
+	capacityHint int
+	// logger optionally traces set decisions. A nil logger disables logging.
+	logger log.Logger
+	// memoryPressure stops new entries from being added while set
+	memoryPressure bool
+	// lastPruneDuration is how long the last call to Prune held the lock
+	lastPruneDuration time.Duration
+	// age lists the keys of the entries in the order they were added, which
+	// is also the order of the times they were first seen
+	age *list.List
+	// weights optionally ranks peers so that Pop prefers the higher weighted
+	// ones. Peers without a weight have a weight of 0.
+	weights map[uint16]int
+	// onFirstSeen is optionally called when a key is seen for the first time
+	onFirstSeen func(txKey types.TxKey)
+	// selectable optionally filters the peers that Pop may return
+	selectable func(peer uint16) bool
+	// peerAdds and peerRemoves count the peers added to and removed from
+	// entries over the lifetime of the set
+	peerAdds    atomic.Uint64
+	peerRemoves atomic.Uint64
+	// maxPeerID optionally bounds the peer IDs accepted by Add. 0 means no
+	// limit. rejectedPeers counts the adds refused for exceeding it.
+	maxPeerID     uint16
+	rejectedPeers atomic.Uint64
+	// ops optionally records the most recent operations for debugging
+	ops *opLog
+	// pruneJitter randomizes the TTL applied by Prune by up to this fraction
+	pruneJitter float64
+	// avgPeers is an exponentially weighted moving average of the number of
+	// peers of the entry touched by each Add and Remove
+	avgPeers float64
+	// highWaterMark is the largest length reached since construction or the
+	// last Reset
+	highWaterMark int
+}
+
+type timestampedPeerSet struct {
+	// peers maps each peer to when it last announced the transaction
+	peers map[uint16]time.Time
+	// order lists the peers in the order they were first seen
+	order []uint16
+	// firstPeer is the peer that created the entry. It is kept even once the
+	// peer is removed.
+	firstPeer uint16
+	time      time.Time
+	// elem is the entry's element in the set's age list
+	elem *list.Element
+	// attempts counts the failed attempts to obtain the transaction, the
+	// last of which was made at lastAttempt. See SeenTxSet.RecordAttempt.
+	attempts    int
+	lastAttempt time.Time
+}
+
+func newTimestampedPeerSet(peer uint16) *timestampedPeerSet {
+	now := time.Now().UTC()
+	return &timestampedPeerSet{
+		peers:     map[uint16]time.Time{peer: now},
+		order:     []uint16{peer},
+		firstPeer: peer,
+		time:      now,
+	}
+}
+
+// addPeer records the peer, refreshing its timestamp if it was already
+// present. It returns false if the peer was already present.
+func (ps *timestampedPeerSet) addPeer(peer uint16) bool {
+	_, has := ps.peers[peer]
+	ps.peers[peer] = time.Now().UTC()
+	if has {
+		return false
+	}
+	ps.order = append(ps.order, peer)
+	return true
+}
+
+// removePeer removes the peer, returning false if it was not present.
+func (ps *timestampedPeerSet) removePeer(peer uint16) bool {
+	if _, has := ps.peers[peer]; !has {
+		return false
+	}
+	delete(ps.peers, peer)
+	for i, p := range ps.order {
+		if p == peer {
+			ps.order = append(ps.order[:i], ps.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func NewSeenTxSet() *SeenTxSet {
+	return NewSeenTxSetWithCapacity(0)
+}
+
+// NewSeenTxSetWithCapacity returns a set that presizes its map with room for
+// capacityHint entries once the first entry is added.
+func NewSeenTxSetWithCapacity(capacityHint int) *SeenTxSet {
+	return &SeenTxSet{
+		capacityHint: capacityHint,
+		weights:      make(map[uint16]int),
+	}
+}
+
+// SetLogger sets a logger to trace set decisions at debug level. Passing nil
+// disables logging.
+func (s *SeenTxSet) SetLogger(logger log.Logger) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.logger = logger
+}
+
+// SetMemoryPressure signals whether the process is under memory pressure.
+// While under pressure the set stops growing: peers are still recorded for
+// transactions that are already tracked but new transactions are ignored.
+func (s *SeenTxSet) SetMemoryPressure(underPressure bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.memoryPressure = underPressure
+}
+
+// SetPeerSelectable sets a predicate consulted by Pop: peers for which it
+// returns false are never popped, allowing higher layers to blacklist peers
+// without the set knowing the policy. The predicate is called while holding
+// the set's lock and must not call back into the set. Passing nil makes every
+// peer selectable again.
+func (s *SeenTxSet) SetPeerSelectable(selectable func(peer uint16) bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.selectable = selectable
+}
+
+// SetMaxPeerID sets the largest peer ID accepted by Add. Adds with a larger ID
+// are ignored and counted by RejectedPeers, surfacing peer ID mapping bugs.
+// Setting 0 removes the limit.
+func (s *SeenTxSet) SetMaxPeerID(maxPeerID uint16) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.maxPeerID = maxPeerID
+}
+
+// RejectedPeers returns the number of adds ignored because the peer ID exceeded
+// the maximum set with SetMaxPeerID.
+func (s *SeenTxSet) RejectedPeers() uint64 {
+	return s.rejectedPeers.Load()
+}
+
+// EnableOpLog records the last size operations (add, pop, remove and
+// removeKey) for retrieval with RecentOps. This adds overhead to every
+// operation and is meant for debugging. A size of 0 disables the log.
+func (s *SeenTxSet) EnableOpLog(size int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.ops = nil
+	if size > 0 {
+		s.ops = newOpLog(size)
+	}
+}
+
+// RecentOps returns the operations recorded since EnableOpLog, oldest first.
+func (s *SeenTxSet) RecentOps() []CacheOp {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.ops.recent()
+}
+
+// SetPeerWeight sets the weight of a peer. When popping a peer for a
+// transaction, peers with a higher weight are chosen first. Setting a weight of
+// 0 restores the default.
+func (s *SeenTxSet) SetPeerWeight(peer uint16, weight int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if weight == 0 {
+		delete(s.weights, peer)
+		return
+	}
+	s.weights[peer] = weight
+}
+
+// SetOnFirstSeen sets a callback that is invoked, outside of the set's lock,
+// whenever Add records the first peer for a key. Passing nil removes it.
+func (s *SeenTxSet) SetOnFirstSeen(onFirstSeen func(txKey types.TxKey)) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.onFirstSeen = onFirstSeen
+}
+
+func (s *SeenTxSet) Add(txKey types.TxKey, peer uint16) {
+	if peer == 0 {
+		return
+	}
+	if onFirstSeen := s.add(txKey, peer); onFirstSeen != nil {
+		onFirstSeen(txKey)
+	}
+}
+
+// add records the peer for the key. If this created a new entry, it returns
+// the callback to notify that the key was seen for the first time.
+func (s *SeenTxSet) add(txKey types.TxKey, peer uint16) func(types.TxKey) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, onFirstSeen := s.record(txKey, peer)
+	return onFirstSeen
+}
+
+// AddDigest records the peer for each key of a digest it announced, skipping
+// the keys for which have, if non nil, returns true, e.g. transactions already
+// in the mempool. have is called without the set locked. It returns the number
+// of keys the peer was recorded for.
+func (s *SeenTxSet) AddDigest(peer uint16, keys []types.TxKey, have func(types.TxKey) bool) int {
+	if peer == 0 {
+		return 0
+	}
+	wanted := make([]types.TxKey, 0, len(keys))
+	for _, txKey := range keys {
+		if have == nil || !have(txKey) {
+			wanted = append(wanted, txKey)
+		}
+	}
+
+	var (
+		recorded    int
+		firstSeen   []types.TxKey
+		onFirstSeen func(types.TxKey)
+	)
+	s.mtx.Lock()
+	for _, txKey := range wanted {
+		ok, callback := s.record(txKey, peer)
+		if ok {
+			recorded++
+		}
+		if callback != nil {
+			onFirstSeen = callback
+			firstSeen = append(firstSeen, txKey)
+		}
+	}
+	s.mtx.Unlock()
+
+	for _, txKey := range firstSeen {
+		onFirstSeen(txKey)
+	}
+	return recorded
+}
+
+// record records the peer for the key and reports whether it did so, which it
+// does not for out of range peers or, under memory pressure, for new keys. If
+// this created a new entry, it also returns the callback to notify that the key
+// was seen for the first time.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) record(txKey types.TxKey, peer uint16) (bool, func(types.TxKey)) {
+	if s.maxPeerID != 0 && peer > s.maxPeerID {
+		s.rejectedPeers.Add(1)
+		if s.logger != nil {
+			s.logger.Error("rejected out of range peer ID", "txKey", txKey, "peer", peer, "max", s.maxPeerID)
+		}
+		return false, nil
+	}
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		if s.memoryPressure {
+			return false, nil
+		}
+		s.insertEntry(txKey, newTimestampedPeerSet(peer))
+		s.samplePeers(1)
+		s.ops.record("add", txKey, true)
+		if len(s.set) > s.highWaterMark {
+			s.highWaterMark = len(s.set)
+		}
+		s.peerAdds.Add(1)
+		if s.logger != nil {
+			s.logger.Debug("first peer has seen tx", "txKey", txKey, "peer", peer)
+		}
+		return true, s.onFirstSeen
+	}
+	added := seenSet.addPeer(peer)
+	if added {
+		s.peerAdds.Add(1)
+	}
+	s.samplePeers(len(seenSet.peers))
+	s.ops.record("add", txKey, added)
+	if s.logger != nil {
+		s.logger.Debug("additional peer has seen tx", "txKey", txKey, "peer", peer)
+	}
+	return true, nil
+}
+
+func (s *SeenTxSet) Pop(txKey types.TxKey) uint16 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return 0
+	}
+	best := s.pick(seenSet)
+	if seenSet.removePeer(best) {
+		s.peerRemoves.Add(1)
+	}
+	s.ops.record("pop", txKey, best != 0)
+	return best
+}
+
+// PopAndRemove returns a peer of the entry, chosen as by Pop, and deletes the
+// whole entry in one step. ok is false if the transaction is not tracked or
+// none of its peers are selectable; the entry is deleted either way.
+func (s *SeenTxSet) PopAndRemove(txKey types.TxKey) (peer uint16, ok bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return 0, false
+	}
+	peer = s.pick(seenSet)
+	s.removeKey(txKey)
+	s.ops.record("pop", txKey, peer != 0)
+	return peer, peer != 0
+}
+
+// pick returns the selectable peer with the highest weight, or 0 if none of the
+// entry's peers are selectable.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) pick(seenSet *timestampedPeerSet) uint16 {
+	var (
+		best       uint16
+		bestWeight int
+	)
+	for peer := range seenSet.peers {
+		if s.selectable != nil && !s.selectable(peer) {
+			continue
+		}
+		if weight := s.weights[peer]; best == 0 || weight > bestWeight {
+			best, bestWeight = peer, weight
+		}
+	}
+	return best
+}
+
+func (s *SeenTxSet) RemoveKey(txKey types.TxKey) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.ops.record("removeKey", txKey, s.removeKey(txKey))
+}
+
+// removeKey deletes the entry for the key, counting its peers as removed. It
+// returns whether there was an entry.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) removeKey(txKey types.TxKey) bool {
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return false
+	}
+	s.peerRemoves.Add(uint64(len(seenSet.peers)))
+	s.deleteEntry(txKey)
+	return true
+}
+
+// RemoveKeyReported removes the entry for the key and reports whether there
+// was one to remove.
+func (s *SeenTxSet) RemoveKeyReported(txKey types.TxKey) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	removed := s.removeKey(txKey)
+	s.ops.record("removeKey", txKey, removed)
+	return removed
+}
+
+// Remove removes the peer from the set of peers that have seen the key. The
+// entry for the key is deleted once its last peer is removed.
+func (s *SeenTxSet) Remove(txKey types.TxKey, peer uint16) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	set, exists := s.set[txKey]
+	if exists {
+		removed := set.removePeer(peer)
+		if removed {
+			s.peerRemoves.Add(1)
+		}
+		s.ops.record("remove", txKey, removed)
+		// drop the entry once no peers remain so that empty sets don't linger
+		if len(set.peers) == 0 {
+			s.deleteEntry(txKey)
+		} else {
+			s.samplePeers(len(set.peers))
+		}
+	}
+}
+
+// avgPeersAlpha is the weight of each new sample in AvgPeersPerTx. Samples
+// older than about 1/avgPeersAlpha operations have little influence.
+const avgPeersAlpha = 0.05
+
+// AvgPeersPerTx returns an exponentially weighted moving average of the
+// number of peers per transaction, sampled from the entry touched by each Add
+// and by each Remove that leaves the entry in place. It trends gossip fan-in.
+func (s *SeenTxSet) AvgPeersPerTx() float64 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.avgPeers
+}
+
+// samplePeers folds the peer count of an entry into the moving average. The
+// first sample initializes it.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) samplePeers(peers int) {
+	if s.avgPeers == 0 {
+		s.avgPeers = float64(peers)
+		return
+	}
+	s.avgPeers += avgPeersAlpha * (float64(peers) - s.avgPeers)
+}
+
+// SetPruneJitter randomizes the TTL applied by each call to Prune within
+// ±fraction of the TTL implied by its limit, so that nodes pruning on the same
+// schedule do not re-request transactions in sync. A fraction of 0 disables
+// jitter.
+func (s *SeenTxSet) SetPruneJitter(fraction float64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.pruneJitter = fraction
+}
+
+// pruneLimit applies the prune jitter to the limit, treating the time between
+// the limit and now as the TTL.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) pruneLimit(limit time.Time, now time.Time) time.Time {
+	ttl := now.Sub(limit)
+	if s.pruneJitter == 0 || ttl <= 0 {
+		return limit
+	}
+	jitter := (2*cmtrand.Float64() - 1) * s.pruneJitter * float64(ttl)
+	return limit.Add(time.Duration(jitter))
+}
+
+// Prune removes all entries first seen before the limit, adjusted by the prune
+// jitter if set. The time taken is recorded and reported by LastPruneDuration.
+func (s *SeenTxSet) Prune(limit time.Time) {
+	s.prune(limit, false)
+}
+
+// PruneAndCollect is like Prune but returns the keys of the removed entries,
+// e.g. so that the reactor knows which transactions it gave up tracking.
+func (s *SeenTxSet) PruneAndCollect(limit time.Time) []types.TxKey {
+	return s.prune(limit, true)
+}
+
+// prune implements Prune, returning the removed keys if collect is set.
+func (s *SeenTxSet) prune(limit time.Time, collect bool) []types.TxKey {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	start := time.Now()
+	defer func() { s.lastPruneDuration = time.Since(start) }()
+	limit = s.pruneLimit(limit, start)
+	var pruned []types.TxKey
+	for key, seenSet := range s.set {
+		if seenSet.time.Before(limit) {
+			s.deleteEntry(key)
+			if collect {
+				pruned = append(pruned, key)
+			}
+			if s.logger != nil {
+				s.logger.Debug("pruned seen tx", "txKey", key)
+			}
+		}
+	}
+	return pruned
+}
+
+// RemapPeer moves every sighting recorded under oldID to newID, e.g. when a
+// reconnecting peer is given a new ID. Where newID already saw the transaction
+// the sightings are merged, keeping the most recent announcement time. It
+// returns the number of entries changed.
+func (s *SeenTxSet) RemapPeer(oldID, newID uint16) int {
+	if oldID == newID || newID == 0 {
+		return 0
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	touched := 0
+	for _, seenSet := range s.set {
+		announced, ok := seenSet.peers[oldID]
+		if !ok {
+			continue
+		}
+		touched++
+		delete(seenSet.peers, oldID)
+		if seenSet.firstPeer == oldID {
+			seenSet.firstPeer = newID
+		}
+		if existing, ok := seenSet.peers[newID]; ok {
+			if announced.After(existing) {
+				seenSet.peers[newID] = announced
+			}
+			for i, peer := range seenSet.order {
+				if peer == oldID {
+					seenSet.order = append(seenSet.order[:i], seenSet.order[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		seenSet.peers[newID] = announced
+		for i, peer := range seenSet.order {
+			if peer == oldID {
+				seenSet.order[i] = newID
+				break
+			}
+		}
+	}
+	return touched
+}
+
+// PruneRange removes all entries first seen within [from, to) and returns how
+// many were removed.
+func (s *SeenTxSet) PruneRange(from, to time.Time) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	pruned := 0
+	for key, seenSet := range s.set {
+		if !seenSet.time.Before(from) && seenSet.time.Before(to) {
+			s.deleteEntry(key)
+			pruned++
+			if s.logger != nil {
+				s.logger.Debug("pruned seen tx", "txKey", key)
+			}
+		}
+	}
+	return pruned
+}
+
+// PruneEmpty removes entries that no longer have any peers, e.g. because
+// every peer was popped, and returns how many were removed.
+func (s *SeenTxSet) PruneEmpty() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	pruned := 0
+	for key, seenSet := range s.set {
+		if len(seenSet.peers) == 0 {
+			s.deleteEntry(key)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// PruneIncremental is like Prune, without the jitter, but visits at most
+// maxScan entries per call so that pruning a large set can be spread across
+// many short lock holds. Entries are visited from the oldest, stopping at the
+// first one seen at or after the limit. It returns true if the oldest remaining
+// entry is still due to be pruned. A maxScan below 1 is treated as 1.
+func (s *SeenTxSet) PruneIncremental(limit time.Time, maxScan int) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if maxScan < 1 {
+		maxScan = 1
+	}
+	for i := 0; i < maxScan; i++ {
+		key, seenSet := s.oldest()
+		if seenSet == nil || !seenSet.time.Before(limit) {
+			return false
+		}
+		s.deleteEntry(key)
+	}
+	_, seenSet := s.oldest()
+	return seenSet != nil && seenSet.time.Before(limit)
+}
+
+// oldest returns the entry added the longest time ago, or a nil entry if the
+// set is empty.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) oldest() (types.TxKey, *timestampedPeerSet) {
+	if s.age == nil || s.age.Len() == 0 {
+		return types.TxKey{}, nil
+	}
+	key := s.age.Front().Value.(types.TxKey)
+	return key, s.set[key]
+}
+
+// insertEntry adds the entry for the key as the newest in the set.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) insertEntry(txKey types.TxKey, seenSet *timestampedPeerSet) {
+	if s.set == nil {
+		s.set = make(map[types.TxKey]*timestampedPeerSet, s.capacityHint)
+	}
+	if s.age == nil {
+		s.age = list.New()
+	}
+	seenSet.elem = s.age.PushBack(txKey)
+	s.set[txKey] = seenSet
+}
+
+// deleteEntry removes the entry for the key, if any.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) deleteEntry(txKey types.TxKey) {
+	if seenSet, exists := s.set[txKey]; exists {
+		s.age.Remove(seenSet.elem)
+		delete(s.set, txKey)
+	}
+}
+
+// PrunePeers removes the individual peers that last announced a transaction
+// before the limit, deleting an entry only once all of its peers are gone. This
+// keeps peer routing accurate without dropping entries that still have fresh
+// sightings.
+func (s *SeenTxSet) PrunePeers(limit time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for key, seenSet := range s.set {
+		for peer, seen := range seenSet.peers {
+			if seen.Before(limit) && seenSet.removePeer(peer) {
+				s.peerRemoves.Add(1)
+			}
+		}
+		if len(seenSet.peers) == 0 {
+			s.deleteEntry(key)
+		}
+	}
+}
+
+func (s *SeenTxSet) Has(txKey types.TxKey, peer uint16) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return false
+	}
+	_, has := seenSet.peers[peer]
+	return has
+}
+
+// FirstPeer returns the peer that first announced the transaction, even if it
+// has since been removed from the entry. ok is false if the transaction is not
+// tracked.
+func (s *SeenTxSet) FirstPeer(txKey types.TxKey) (peer uint16, ok bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return 0, false
+	}
+	return seenSet.firstPeer, true
+}
+
+func (s *SeenTxSet) Get(txKey types.TxKey) map[uint16]struct{} {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return nil
+	}
+	// make a copy of the struct to avoid concurrency issues
+	peers := make(map[uint16]struct{}, len(seenSet.peers))
+	for peer := range seenSet.peers {
+		peers[peer] = struct{}{}
+	}
+	return peers
+}
+
+// evictOldest removes the entry that was added the longest time ago. It
+// returns false if the set is empty.
+func (s *SeenTxSet) evictOldest() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key, oldest := s.oldest()
+	if oldest == nil {
+		return false
+	}
+	s.deleteEntry(key)
+	return true
+}
+
+// UnseenBy filters the candidate keys down to those that the given peer has
+// not yet seen. The order of the candidates is preserved.
+func (s *SeenTxSet) UnseenBy(peer uint16, candidates []types.TxKey) []types.TxKey {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	unseen := make([]types.TxKey, 0, len(candidates))
+	for _, txKey := range candidates {
+		if seenSet, exists := s.set[txKey]; exists {
+			if _, has := seenSet.peers[peer]; has {
+				continue
+			}
+		}
+		unseen = append(unseen, txKey)
+	}
+	return unseen
+}
+
+// TxsSeenByPeer returns a snapshot of all keys the given peer has announced.
+func (s *SeenTxSet) TxsSeenByPeer(peer uint16) []types.TxKey {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	keys := make([]types.TxKey, 0)
+	for txKey, seenSet := range s.set {
+		if _, has := seenSet.peers[peer]; has {
+			keys = append(keys, txKey)
+		}
+	}
+	return keys
+}
+
+// summary returns the number of distinct peers across all entries and the
+// time the oldest entry was first seen. The time is zero if the set is empty.
+func (s *SeenTxSet) summary() (peers int, oldest time.Time) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	distinct := make(map[uint16]struct{})
+	for _, seenSet := range s.set {
+		for peer := range seenSet.peers {
+			distinct[peer] = struct{}{}
+		}
+		if oldest.IsZero() || seenSet.time.Before(oldest) {
+			oldest = seenSet.time
+		}
+	}
+	return len(distinct), oldest
+}
+
+// SinglySeen returns the transactions announced by exactly one peer. These
+// are the most at risk of becoming unavailable should that peer disconnect.
+func (s *SeenTxSet) SinglySeen() []types.TxKey {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	var keys []types.TxKey
+	for txKey, seenSet := range s.set {
+		if len(seenSet.peers) == 1 {
+			keys = append(keys, txKey)
+		}
+	}
+	return keys
+}
+
+// TopSeen returns up to n keys with the largest peer sets, ordered from the
+// most to the least announced. It returns nil if n is not positive.
+func (s *SeenTxSet) TopSeen(n int) []types.TxKey {
+	if n <= 0 {
+		return nil
+	}
+	type keyCount struct {
+		key   types.TxKey
+		count int
+	}
+	s.mtx.RLock()
+	counts := make([]keyCount, 0, len(s.set))
+	for key, seenSet := range s.set {
+		counts = append(counts, keyCount{key, len(seenSet.peers)})
+	}
+	s.mtx.RUnlock()
+
+	sort.Slice(counts, func(i, j int) bool { return counts[i].count > counts[j].count })
+	if n > len(counts) {
+		n = len(counts)
+	}
+	keys := make([]types.TxKey, n)
+	for i := range keys {
+		keys[i] = counts[i].key
+	}
+	return keys
+}
+
+// PeersSeeingAll returns, in ascending order, the peers that have seen every
+// one of the keys. It returns an empty slice if there is no common peer.
+func (s *SeenTxSet) PeersSeeingAll(keys []types.TxKey) []uint16 {
+	peers := make([]uint16, 0)
+	if len(keys) == 0 {
+		return peers
+	}
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	first, exists := s.set[keys[0]]
+	if !exists {
+		return peers
+	}
+	for peer := range first.peers {
+		seenAll := true
+		for _, txKey := range keys[1:] {
+			seenSet, exists := s.set[txKey]
+			if !exists {
+				return peers[:0]
+			}
+			if _, has := seenSet.peers[peer]; !has {
+				seenAll = false
+				break
+			}
+		}
+		if seenAll {
+			peers = append(peers, peer)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	return peers
+}
+
+// CommonPeers returns, in ascending order, the peers that have seen both
+// transactions, e.g. to batch requests for them to a single peer.
+func (s *SeenTxSet) CommonPeers(a, b types.TxKey) []uint16 {
+	return s.PeersSeeingAll([]types.TxKey{a, b})
+}
+
+const (
+	// attemptBackoffBase is how long to wait after the first failed attempt
+	// to obtain a seen transaction. The wait doubles with every further
+	// attempt, up to attemptBackoffMax.
+	attemptBackoffBase = time.Second
+	attemptBackoffMax  = time.Minute
+)
+
+// RecordAttempt records an attempt, made at now, to obtain the transaction from
+// its peers, backing off further attempts. See NextAttemptAllowed. It is a
+// no-op if the transaction is not tracked.
+func (s *SeenTxSet) RecordAttempt(txKey types.TxKey, now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return
+	}
+	seenSet.attempts++
+	seenSet.lastAttempt = now
+}
+
+// NextAttemptAllowed reports whether enough time has passed since the last
+// recorded attempt to obtain the transaction to try again. The backoff starts
+// at attemptBackoffBase and doubles with every attempt, up to
+// attemptBackoffMax. It returns true if no attempt was recorded.
+func (s *SeenTxSet) NextAttemptAllowed(txKey types.TxKey, now time.Time) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	seenSet, exists := s.set[txKey]
+	if !exists || seenSet.attempts == 0 {
+		return true
+	}
+	backoff := attemptBackoffMax
+	if shift := seenSet.attempts - 1; shift < 6 {
+		if doubled := attemptBackoffBase << shift; doubled < backoff {
+			backoff = doubled
+		}
+	}
+	return !now.Before(seenSet.lastAttempt.Add(backoff))
+}
+
+// scoreHalfLife is the age at which an entry's score is halved
+const scoreHalfLife = 30 * time.Second
+
+// Score rates how worthwhile it is to request a seen transaction, favouring
+// ones announced recently and by many peers. The score is the number of peers
+// halved for every scoreHalfLife elapsed since the most recent announcement:
+// two peers announcing 30s ago score the same as one peer announcing now. It
+// returns 0 if the transaction has not been seen.
+func (s *SeenTxSet) Score(txKey types.TxKey, now time.Time) float64 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	seenSet, exists := s.set[txKey]
+	if !exists || len(seenSet.peers) == 0 {
+		return 0
+	}
+	var latest time.Time
+	for _, announced := range seenSet.peers {
+		if announced.After(latest) {
+			latest = announced
+		}
+	}
+	age := now.Sub(latest)
+	if age < 0 {
+		age = 0
+	}
+	return float64(len(seenSet.peers)) * math.Exp2(-age.Seconds()/scoreHalfLife.Seconds())
+}
+
+// LastPruneDuration returns how long the most recent Prune took. Operators can
+// use it to detect when pruning becomes a source of latency.
+func (s *SeenTxSet) LastPruneDuration() time.Duration {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.lastPruneDuration
+}
+
+// PeerAdds returns the number of times a peer was added to an entry over the
+// lifetime of the set. Together with PeerRemoves this gives the churn rate.
+func (s *SeenTxSet) PeerAdds() uint64 {
+	return s.peerAdds.Load()
+}
+
+// PeerRemoves returns the number of times a peer was removed from an entry by
+// Pop, Remove or RemoveKey over the lifetime of the set.
+func (s *SeenTxSet) PeerRemoves() uint64 {
+	return s.peerRemoves.Load()
+}
+
+// GetBounded returns at most limit of the peers that have seen the key, in the
+// order they were first seen. Copying only a few peers keeps the lock hold
+// short for widely announced transactions. A negative limit is treated as 0.
+func (s *SeenTxSet) GetBounded(txKey types.TxKey, limit int) []uint16 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	seenSet, exists := s.set[txKey]
+	if !exists {
+		return nil
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	if limit > len(seenSet.order) {
+		limit = len(seenSet.order)
+	}
+	peers := make([]uint16, limit)
+	copy(peers, seenSet.order)
+	return peers
+}
+
+// ToProto returns a snapshot of the set's entries, ordered by key, in their
+// protobuf representation.
+func (s *SeenTxSet) ToProto() *protomem.SeenTxSet {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	pb := &protomem.SeenTxSet{
+		Entries: make([]protomem.SeenTxSetEntry, 0, len(s.set)),
+	}
+	for txKey, seenSet := range s.set {
+		key := txKey
+		entry := protomem.SeenTxSetEntry{
+			TxKey:     key[:],
+			Time:      seenSet.time,
+			Peers:     make([]protomem.SeenTxSetPeer, len(seenSet.order)),
+			FirstPeer: uint32(seenSet.firstPeer),
+		}
+		for i, peer := range seenSet.order {
+			entry.Peers[i] = protomem.SeenTxSetPeer{Peer: uint32(peer), Time: seenSet.peers[peer]}
+		}
+		pb.Entries = append(pb.Entries, entry)
+	}
+	sort.Slice(pb.Entries, func(i, j int) bool {
+		return bytes.Compare(pb.Entries[i].TxKey, pb.Entries[j].TxKey) < 0
+	})
+	return pb
+}
+
+// SeenTxSetFromProto builds a set from its protobuf representation, returning
+// an error if any key or peer ID is invalid.
+func SeenTxSetFromProto(pb *protomem.SeenTxSet) (*SeenTxSet, error) {
+	s := NewSeenTxSetWithCapacity(len(pb.Entries))
+	// add the entries from the oldest so that the age order is restored
+	entries := make([]protomem.SeenTxSetEntry, len(pb.Entries))
+	copy(entries, pb.Entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	for _, entry := range entries {
+		txKey, err := types.TxKeyFromBytes(entry.TxKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(entry.Peers) == 0 {
+			return nil, fmt.Errorf("seen tx %v has no peers", txKey)
+		}
+		if entry.FirstPeer > math.MaxUint16 {
+			return nil, fmt.Errorf("seen tx %v has invalid first peer ID %d", txKey, entry.FirstPeer)
+		}
+		seenSet := &timestampedPeerSet{
+			peers:     make(map[uint16]time.Time, len(entry.Peers)),
+			order:     make([]uint16, 0, len(entry.Peers)),
+			firstPeer: uint16(entry.FirstPeer),
+			time:      entry.Time,
+		}
+		for _, peer := range entry.Peers {
+			if peer.Peer == 0 || peer.Peer > math.MaxUint16 {
+				return nil, fmt.Errorf("seen tx %v has invalid peer ID %d", txKey, peer.Peer)
+			}
+			if seenSet.addPeer(uint16(peer.Peer)) {
+				seenSet.peers[uint16(peer.Peer)] = peer.Time
+			}
+		}
+		s.deleteEntry(txKey)
+		s.insertEntry(txKey, seenSet)
+	}
+	s.highWaterMark = len(s.set)
+	return s, nil
+}
+
+// Len returns the amount of cached items. Mostly used for testing.
+func (s *SeenTxSet) Len() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return len(s.set)
+}
+
+func (s *SeenTxSet) Reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.reset()
+}
+
+// reset empties the set.
+// This assumes that the set's mutex is already locked.
+func (s *SeenTxSet) reset() {
+	s.set = nil
+	s.age = nil
+	s.highWaterMark = 0
+}
+
+// HighWaterMark returns the largest number of entries held at once since the
+// set was constructed or last reset.
+func (s *SeenTxSet) HighWaterMark() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.highWaterMark
+}
+
+// SentTxSet records which peers we have forwarded each transaction to, so that
+// a transaction is not gossiped to the same peer twice. It mirrors SeenTxSet,
+// which records the peers we have received announcements from.
+type SentTxSet struct {
+	mtx tmsync.RWMutex
+	set map[types.TxKey]*sentPeerSet
+}
+
+// sentPeerSet is the set of peers a transaction was sent to and when it was
+// first sent
+type sentPeerSet struct {
+	peers map[uint16]struct{}
+	time  time.Time
+}
+
+func NewSentTxSet() *SentTxSet {
+	return &SentTxSet{
+		set: make(map[types.TxKey]*sentPeerSet),
+	}
+}
+
+// Add records that the transaction was sent to the peer. Peer 0 is ignored.
+func (s *SentTxSet) Add(txKey types.TxKey, peer uint16) {
+	if peer == 0 {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	sentSet, exists := s.set[txKey]
+	if !exists {
+		s.set[txKey] = &sentPeerSet{
+			peers: map[uint16]struct{}{peer: {}},
+			time:  time.Now().UTC(),
+		}
+		return
+	}
+	sentSet.peers[peer] = struct{}{}
+}
+
+// Has reports whether the transaction was sent to the peer.
+func (s *SentTxSet) Has(txKey types.TxKey, peer uint16) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	sentSet, exists := s.set[txKey]
+	if !exists {
+		return false
+	}
+	_, has := sentSet.peers[peer]
+	return has
+}
+
+// RemoveKey forgets every send of the transaction.
+func (s *SentTxSet) RemoveKey(txKey types.TxKey) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.set, txKey)
+}
+
+// Prune removes all entries first sent before the limit.
+func (s *SentTxSet) Prune(limit time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for key, sentSet := range s.set {
+		if sentSet.time.Before(limit) {
+			delete(s.set, key)
+		}
+	}
+}
+
+// Len returns the amount of tracked transactions. Mostly used for testing.
+func (s *SentTxSet) Len() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return len(s.set)
 }
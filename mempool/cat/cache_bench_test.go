@@ -0,0 +1,190 @@
+package cat
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+func BenchmarkSeenTxSetWarmup(b *testing.B) {
+	const numTxs = 10000
+	keys := make([]types.TxKey, numTxs)
+	for i := range keys {
+		keys[i] = types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+	}
+
+	for _, capacityHint := range []int{0, numTxs} {
+		b.Run(fmt.Sprintf("capacity=%d", capacityHint), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				seenSet := NewSeenTxSetWithCapacity(capacityHint)
+				for _, key := range keys {
+					seenSet.Add(key, 1)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLRUTxCacheReset(b *testing.B) {
+	const size = 10000
+	keys := make([]types.TxKey, size)
+	for i := range keys {
+		keys[i] = types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+	}
+
+	for _, clearInPlace := range []bool{false, true} {
+		b.Run(fmt.Sprintf("clearInPlace=%t", clearInPlace), func(b *testing.B) {
+			cache := NewLRUTxCache(size)
+			cache.SetClearInPlace(clearInPlace)
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				for _, key := range keys {
+					cache.Push(key)
+				}
+				b.StartTimer()
+				cache.Reset()
+			}
+		})
+	}
+}
+
+func BenchmarkSeenTxSetGet(b *testing.B) {
+	const numPeers = 1000
+	txKey := types.Tx("tx").Key()
+	seenSet := NewSeenTxSet()
+	for peer := uint16(1); peer <= numPeers; peer++ {
+		seenSet.Add(txKey, peer)
+	}
+
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			seenSet.Get(txKey)
+		}
+	})
+	b.Run("GetBounded", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			seenSet.GetBounded(txKey, 5)
+		}
+	})
+}
+
+func BenchmarkLRUTxCacheLocking(b *testing.B) {
+	const size = 10000
+	keys := make([]types.TxKey, size)
+	for i := range keys {
+		keys[i] = types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+	}
+
+	for _, unsynchronized := range []bool{false, true} {
+		newCache := NewLRUTxCache
+		if unsynchronized {
+			newCache = NewUnsynchronizedLRUTxCache
+		}
+		b.Run(fmt.Sprintf("unsynchronized=%t/Push", unsynchronized), func(b *testing.B) {
+			cache := newCache(size)
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				cache.Push(keys[n%size])
+			}
+		})
+		b.Run(fmt.Sprintf("unsynchronized=%t/Has", unsynchronized), func(b *testing.B) {
+			cache := newCache(size)
+			for _, key := range keys {
+				cache.Push(key)
+			}
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				cache.Has(keys[n%size])
+			}
+		})
+	}
+}
+
+// BenchmarkSeenTxSetConcurrentReads compares a Mutex and an RWMutex guarding
+// the lookup done by SeenTxSet.Has under parallel readers, alongside the
+// SeenTxSet itself. Run with -cpu to vary the number of readers.
+func BenchmarkSeenTxSetConcurrentReads(b *testing.B) {
+	const numTxs = 1000
+	keys := make([]types.TxKey, numTxs)
+	seenSet := NewSeenTxSet()
+	for i := range keys {
+		keys[i] = types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+		seenSet.Add(keys[i], 1)
+		seenSet.Add(keys[i], 2)
+	}
+	has := func(txKey types.TxKey) bool {
+		seen, ok := seenSet.set[txKey]
+		if !ok {
+			return false
+		}
+		_, ok = seen.peers[1]
+		return ok
+	}
+
+	b.Run("Mutex", func(b *testing.B) {
+		var mtx sync.Mutex
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				mtx.Lock()
+				has(keys[i%numTxs])
+				mtx.Unlock()
+			}
+		})
+	})
+	b.Run("RWMutex", func(b *testing.B) {
+		var mtx sync.RWMutex
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				mtx.RLock()
+				has(keys[i%numTxs])
+				mtx.RUnlock()
+			}
+		})
+	})
+	b.Run("SeenTxSet", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				seenSet.Has(keys[i%numTxs], 1)
+				seenSet.Get(keys[i%numTxs])
+				seenSet.Len()
+			}
+		})
+	})
+}
+
+// BenchmarkLRUTxCacheConcurrent runs parallel workloads mixing Has and Push
+// calls, from read heavy to write heavy. Run with -cpu to vary the number of
+// goroutines.
+func BenchmarkLRUTxCacheConcurrent(b *testing.B) {
+	const size = 10000
+	keys := make([]types.TxKey, 2*size)
+	for i := range keys {
+		keys[i] = types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+	}
+
+	for _, pushesPer100 := range []int{0, 10, 50, 100} {
+		b.Run(fmt.Sprintf("push=%d%%", pushesPer100), func(b *testing.B) {
+			cache := NewLRUTxCache(size)
+			for _, key := range keys[:size] {
+				cache.Push(key)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					key := keys[i%len(keys)]
+					if i%100 < pushesPer100 {
+						cache.Push(key)
+					} else {
+						cache.Has(key)
+					}
+				}
+			})
+		})
+	}
+}
@@ -1,14 +1,19 @@
 package cat
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/cometbft/cometbft/libs/log"
+	protomem "github.com/cometbft/cometbft/proto/tendermint/mempool"
 	"github.com/cometbft/cometbft/types"
 )
 
@@ -40,6 +45,88 @@ func TestSeenTxSet(t *testing.T) {
 	require.Equal(t, peer1, seenSet.Pop(tx3Key))
 }
 
+func TestSentTxSet(t *testing.T) {
+	var (
+		tx1Key        = types.Tx("tx1").Key()
+		tx2Key        = types.Tx("tx2").Key()
+		tx3Key        = types.Tx("tx3").Key()
+		peer1  uint16 = 1
+		peer2  uint16 = 2
+	)
+
+	sentSet := NewSentTxSet()
+	require.False(t, sentSet.Has(tx1Key, peer1))
+
+	sentSet.Add(tx1Key, peer1)
+	sentSet.Add(tx1Key, peer1)
+	require.Equal(t, 1, sentSet.Len())
+	sentSet.Add(tx1Key, peer2)
+	require.True(t, sentSet.Has(tx1Key, peer1))
+	require.True(t, sentSet.Has(tx1Key, peer2))
+	sentSet.Add(tx2Key, peer1)
+	sentSet.Add(tx3Key, peer1)
+	require.Equal(t, 3, sentSet.Len())
+	require.False(t, sentSet.Has(tx2Key, peer2))
+	sentSet.RemoveKey(tx2Key)
+	require.Equal(t, 2, sentSet.Len())
+	require.False(t, sentSet.Has(tx2Key, peer1))
+
+	sentSet.Add(tx2Key, 0)
+	require.False(t, sentSet.Has(tx2Key, 0))
+}
+
+func TestSentTxSetPrune(t *testing.T) {
+	keys := testTxKeys(3)
+	sentSet := NewSentTxSet()
+	for _, key := range keys {
+		sentSet.Add(key, 1)
+	}
+	sentSet.set[keys[0]].time = time.Now().UTC().Add(-time.Hour)
+
+	sentSet.Prune(time.Now().UTC().Add(-time.Minute))
+	require.Equal(t, 2, sentSet.Len())
+	require.False(t, sentSet.Has(keys[0], 1))
+	require.True(t, sentSet.Has(keys[1], 1))
+
+	sentSet.Prune(time.Now().UTC().Add(time.Minute))
+	require.Zero(t, sentSet.Len())
+}
+
+func TestSentTxSetConcurrency(t *testing.T) {
+	const (
+		concurrency = 10
+		numTx       = 100
+	)
+	keys := testTxKeys(numTx)
+	sentSet := NewSentTxSet()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(3)
+		peer := uint16(i%2 + 1)
+		go func() {
+			defer wg.Done()
+			for _, key := range keys {
+				sentSet.Add(key, peer)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, key := range keys {
+				sentSet.Has(key, peer)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := numTx - 1; i >= 0; i-- {
+				sentSet.RemoveKey(keys[i])
+			}
+		}()
+	}
+	wg.Wait()
+	require.LessOrEqual(t, sentSet.Len(), numTx)
+}
+
 func TestLRUTxCacheRemove(t *testing.T) {
 	cache := NewLRUTxCache(100)
 	numTxs := 10
@@ -149,3 +236,1888 @@ func TestLRUTxCacheConcurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestSeenTxSetUnseenBy(t *testing.T) {
+	var (
+		tx1Key        = types.Tx("tx1").Key()
+		tx2Key        = types.Tx("tx2").Key()
+		tx3Key        = types.Tx("tx3").Key()
+		peer1  uint16 = 1
+		peer2  uint16 = 2
+	)
+
+	seenSet := NewSeenTxSet()
+	seenSet.Add(tx1Key, peer1)
+	seenSet.Add(tx2Key, peer2)
+	seenSet.Add(tx3Key, peer1)
+
+	candidates := []types.TxKey{tx1Key, tx2Key, tx3Key}
+	require.Equal(t, []types.TxKey{tx2Key}, seenSet.UnseenBy(peer1, candidates))
+	require.Equal(t, []types.TxKey{tx1Key, tx3Key}, seenSet.UnseenBy(peer2, candidates))
+	require.Equal(t, candidates, seenSet.UnseenBy(3, candidates))
+	require.Empty(t, seenSet.UnseenBy(peer1, nil))
+}
+
+// TestLRUTxCacheConcurrentOperations exercises every method of the cache from
+// many goroutines at once. It is intended to be run with -race and checks that
+// the map and the list stay in agreement afterwards.
+func TestLRUTxCacheConcurrentOperations(t *testing.T) {
+	const (
+		size        = 50
+		concurrency = 8
+		numTx       = 200
+	)
+	cache := NewLRUTxCache(size)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < numTx; i++ {
+				key := types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+				switch (worker + i) % 4 {
+				case 0, 1:
+					cache.Push(key)
+				case 2:
+					cache.Has(key)
+				case 3:
+					cache.Remove(key)
+				}
+				if i%97 == 0 {
+					cache.Reset()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, len(cache.cacheMap), cache.list.Len())
+	require.LessOrEqual(t, cache.list.Len(), size)
+}
+
+// TestLRUTxCacheConcurrentReads races read locked lookups against writers,
+// with push based expiry enabled so that readers see expired entries that only
+// writers remove. It is intended to be run with -race.
+func TestLRUTxCacheConcurrentReads(t *testing.T) {
+	const (
+		size    = 50
+		readers = 6
+		numTx   = 500
+	)
+	keys := testTxKeys(numTx)
+	cache := NewLRUTxCache(size)
+	cache.SetPushTTL(size / 2)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, key := range keys {
+				cache.Has(key)
+				cache.Len()
+				cache.Snapshot()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, key := range keys {
+			cache.Push(key)
+			if i%3 == 0 {
+				cache.Remove(keys[i/2])
+			}
+		}
+	}()
+	wg.Wait()
+
+	require.Equal(t, len(cache.cacheMap), cache.list.Len())
+	require.LessOrEqual(t, cache.list.Len(), size)
+	// only the keys pushed within the TTL are reported
+	require.True(t, cache.Has(keys[numTx-1]))
+	require.False(t, cache.Has(keys[numTx-size/2-1]))
+}
+
+// TestSeenTxSetConcurrentOperations exercises every method of the set from
+// many goroutines at once. It is intended to be run with -race.
+func TestSeenTxSetConcurrentOperations(t *testing.T) {
+	const (
+		concurrency = 8
+		numTx       = 200
+	)
+	seenSet := NewSeenTxSet()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			peer := uint16(worker%3 + 1)
+			for i := 0; i < numTx; i++ {
+				key := types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+				switch (worker + i) % 8 {
+				case 0, 1, 2:
+					seenSet.Add(key, peer)
+				case 3:
+					seenSet.Has(key, peer)
+				case 4:
+					seenSet.Get(key)
+				case 5:
+					seenSet.Pop(key)
+				case 6:
+					seenSet.Remove(key, peer)
+				case 7:
+					seenSet.RemoveKey(key)
+				}
+				if i%50 == 0 {
+					seenSet.UnseenBy(peer, []types.TxKey{key})
+					seenSet.Len()
+				}
+				if i%97 == 0 {
+					seenSet.Prune(time.Now().UTC().Add(-time.Millisecond))
+				}
+				if i%151 == 0 {
+					seenSet.Reset()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, seenSet.Len(), numTx)
+}
+
+func TestSeenTxSetAddSecondPeer(t *testing.T) {
+	var (
+		txKey        = types.Tx("tx1").Key()
+		peer1 uint16 = 1
+		peer2 uint16 = 2
+	)
+
+	seenSet := NewSeenTxSet()
+	seenSet.Add(txKey, peer1)
+	require.True(t, seenSet.Has(txKey, peer1))
+	require.False(t, seenSet.Has(txKey, peer2))
+
+	seenSet.Add(txKey, peer2)
+	require.True(t, seenSet.Has(txKey, peer1))
+	require.True(t, seenSet.Has(txKey, peer2))
+	require.Equal(t, 1, seenSet.Len())
+}
+
+func TestSeenTxSetPopPrefersPeerWeight(t *testing.T) {
+	var (
+		txKey        = types.Tx("tx1").Key()
+		slow  uint16 = 1
+		fast  uint16 = 2
+	)
+
+	seenSet := NewSeenTxSet()
+	seenSet.SetPeerWeight(slow, 1)
+	seenSet.SetPeerWeight(fast, 10)
+	seenSet.Add(txKey, slow)
+	seenSet.Add(txKey, fast)
+
+	require.Equal(t, fast, seenSet.Pop(txKey))
+	require.Equal(t, slow, seenSet.Pop(txKey))
+	require.Zero(t, seenSet.Pop(txKey))
+}
+
+func TestSeenTxSetLazyAllocation(t *testing.T) {
+	txKey := types.Tx("tx1").Key()
+	seenSet := NewSeenTxSetWithCapacity(100)
+	require.Nil(t, seenSet.set)
+	require.False(t, seenSet.Has(txKey, 1))
+	require.Zero(t, seenSet.Len())
+
+	seenSet.Add(txKey, 1)
+	require.NotNil(t, seenSet.set)
+	require.True(t, seenSet.Has(txKey, 1))
+
+	seenSet.Reset()
+	require.Nil(t, seenSet.set)
+	require.Zero(t, seenSet.Pop(txKey))
+}
+
+func TestCacheLogging(t *testing.T) {
+	var (
+		tx1Key = types.Tx("tx1").Key()
+		tx2Key = types.Tx("tx2").Key()
+		buf    bytes.Buffer
+	)
+	logger := log.NewTMLogger(log.NewSyncWriter(&buf))
+
+	cache := NewLRUTxCache(1)
+	cache.Push(tx1Key) // not logged
+	cache.SetLogger(logger)
+	cache.Push(tx1Key)
+	cache.Push(tx2Key)
+	require.Contains(t, buf.String(), "tx key already in cache")
+	require.Contains(t, buf.String(), "evicted tx key from cache")
+	require.Contains(t, buf.String(), "admitted tx key to cache")
+	require.Equal(t, 3, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	buf.Reset()
+	seenSet := NewSeenTxSet()
+	seenSet.SetLogger(logger)
+	seenSet.Add(tx1Key, 1)
+	seenSet.Add(tx1Key, 2)
+	seenSet.Prune(time.Now().UTC().Add(time.Second))
+	require.Contains(t, buf.String(), "first peer has seen tx")
+	require.Contains(t, buf.String(), "additional peer has seen tx")
+	require.Contains(t, buf.String(), "pruned seen tx")
+
+	buf.Reset()
+	cache.SetLogger(nil)
+	seenSet.SetLogger(nil)
+	cache.Push(tx1Key)
+	seenSet.Add(tx1Key, 1)
+	require.Zero(t, buf.Len())
+}
+
+func TestLRUTxCacheCompareAndPush(t *testing.T) {
+	const concurrency = 50
+	txKey := types.Tx("tx1").Key()
+	cache := NewLRUTxCache(10)
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		inserted int
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cache.CompareAndPush(txKey) {
+				mtx.Lock()
+				inserted++
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, inserted)
+	require.True(t, cache.Has(txKey))
+	require.False(t, cache.CompareAndPush(txKey))
+}
+
+func TestSeenTxSetRemove(t *testing.T) {
+	var (
+		txKey        = types.Tx("tx1").Key()
+		peer1 uint16 = 1
+		peer2 uint16 = 2
+		peer3 uint16 = 3
+	)
+
+	t.Run("size 1", func(t *testing.T) {
+		seenSet := NewSeenTxSet()
+		seenSet.Add(txKey, peer1)
+		seenSet.Remove(txKey, peer1)
+		require.Zero(t, seenSet.Len())
+		require.Nil(t, seenSet.Get(txKey))
+	})
+
+	t.Run("size 2", func(t *testing.T) {
+		seenSet := NewSeenTxSet()
+		seenSet.Add(txKey, peer1)
+		seenSet.Add(txKey, peer2)
+		seenSet.Remove(txKey, peer1)
+		require.Equal(t, 1, seenSet.Len())
+		require.Equal(t, map[uint16]struct{}{peer2: {}}, seenSet.Get(txKey))
+		seenSet.Remove(txKey, peer2)
+		require.Zero(t, seenSet.Len())
+	})
+
+	t.Run("non member", func(t *testing.T) {
+		seenSet := NewSeenTxSet()
+		seenSet.Add(txKey, peer1)
+		seenSet.Remove(txKey, peer3)
+		require.True(t, seenSet.Has(txKey, peer1))
+
+		seenSet.Add(txKey, peer2)
+		seenSet.Remove(txKey, peer3)
+		require.Equal(t, map[uint16]struct{}{peer1: {}, peer2: {}}, seenSet.Get(txKey))
+	})
+}
+
+func TestSeenTxSetTxsSeenByPeer(t *testing.T) {
+	var (
+		tx1Key        = types.Tx("tx1").Key()
+		tx2Key        = types.Tx("tx2").Key()
+		tx3Key        = types.Tx("tx3").Key()
+		peer1  uint16 = 1
+		peer2  uint16 = 2
+	)
+
+	seenSet := NewSeenTxSet()
+	seenSet.Add(tx1Key, peer1)
+	seenSet.Add(tx1Key, peer2)
+	seenSet.Add(tx2Key, peer1)
+	seenSet.Add(tx3Key, peer2)
+
+	require.ElementsMatch(t, []types.TxKey{tx1Key, tx2Key}, seenSet.TxsSeenByPeer(peer1))
+	require.ElementsMatch(t, []types.TxKey{tx1Key, tx3Key}, seenSet.TxsSeenByPeer(peer2))
+	require.Empty(t, seenSet.TxsSeenByPeer(3))
+}
+
+func TestSeenTxSetRemoveKeyReported(t *testing.T) {
+	txKey := types.Tx("tx1").Key()
+	seenSet := NewSeenTxSet()
+	require.False(t, seenSet.RemoveKeyReported(txKey))
+
+	seenSet.Add(txKey, 1)
+	require.True(t, seenSet.RemoveKeyReported(txKey))
+	require.Zero(t, seenSet.Len())
+	require.False(t, seenSet.RemoveKeyReported(txKey))
+}
+
+func TestCacheMemoryPressure(t *testing.T) {
+	const size = 10
+	keys := testTxKeys(size + 3)
+
+	cache := NewLRUTxCache(size)
+	for _, key := range keys[:size] {
+		cache.Push(key)
+	}
+	require.Equal(t, size, cache.Len())
+
+	cache.SetMemoryPressure(true)
+	cache.Push(keys[size])
+	require.Equal(t, size/2, cache.Len())
+	require.True(t, cache.Has(keys[size]))
+	cache.Push(keys[size+1])
+	require.Equal(t, size/2, cache.Len())
+
+	cache.SetMemoryPressure(false)
+	cache.Push(keys[size+2])
+	require.Equal(t, size/2+1, cache.Len())
+
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 1)
+	seenSet.SetMemoryPressure(true)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[1], 1)
+	require.Equal(t, 1, seenSet.Len())
+	require.True(t, seenSet.Has(keys[0], 2))
+	require.False(t, seenSet.Has(keys[1], 1))
+
+	seenSet.SetMemoryPressure(false)
+	seenSet.Add(keys[1], 1)
+	require.Equal(t, 2, seenSet.Len())
+}
+
+func TestCacheErrors(t *testing.T) {
+	keys := testTxKeys(3)
+
+	_, err := NewLRUTxCacheChecked(-1)
+	require.True(t, errors.Is(err, ErrInvalidSize))
+
+	disabled, err := NewLRUTxCacheChecked(0)
+	require.NoError(t, err)
+	require.True(t, errors.Is(disabled.TryPush(keys[0]), ErrDisabled))
+
+	cache, err := NewLRUTxCacheChecked(2)
+	require.NoError(t, err)
+	require.NoError(t, cache.TryPush(keys[0]))
+	require.NoError(t, cache.TryPush(keys[1]))
+	require.NoError(t, cache.TryPush(keys[0]))
+	require.True(t, errors.Is(cache.TryPush(keys[2]), ErrCacheFull))
+	require.False(t, cache.Has(keys[2]))
+	require.True(t, cache.Has(keys[1]))
+}
+
+func TestLRUTxCacheTryPushNeverEvicts(t *testing.T) {
+	keys := testTxKeys(10)
+	cache := NewLRUTxCache(10)
+	for _, key := range keys[:7] {
+		require.NoError(t, cache.TryPush(key))
+	}
+
+	// memory pressure halves the capacity, so the cache is already full
+	cache.SetMemoryPressure(true)
+	require.ErrorIs(t, cache.TryPush(keys[7]), ErrCacheFull)
+	require.Equal(t, 7, cache.Len())
+	require.Zero(t, cache.Stats().Evictions)
+	for _, key := range keys[:7] {
+		require.True(t, cache.Has(key))
+	}
+
+	// paused eviction does not matter while there is room
+	cache.SetMemoryPressure(false)
+	cache.PauseEviction()
+	for _, key := range keys[7:] {
+		require.NoError(t, cache.TryPush(key))
+	}
+	require.Equal(t, 10, cache.Len())
+	require.ErrorIs(t, cache.TryPush(types.Tx("extra").Key()), ErrCacheFull)
+	cache.ResumeEviction()
+	require.Zero(t, cache.Stats().Evictions)
+}
+
+func TestRecommendedCacheSize(t *testing.T) {
+	testCases := []struct {
+		txPerSecond float64
+		window      time.Duration
+		expected    int
+	}{
+		{0, time.Minute, minRecommendedCacheSize},
+		{-10, time.Minute, minRecommendedCacheSize},
+		{100, -time.Minute, minRecommendedCacheSize},
+		{1, time.Second, minRecommendedCacheSize},
+		{100, 12 * time.Second, 1200},
+		{333.3, 6 * time.Second, 2000},
+		{1000.1, 10 * time.Second, 10001},
+		{1e9, time.Hour, maxRecommendedCacheSize},
+		{math.Inf(1), time.Second, maxRecommendedCacheSize},
+		{math.NaN(), time.Second, minRecommendedCacheSize},
+	}
+	for i, tc := range testCases {
+		require.Equal(t, tc.expected, RecommendedCacheSize(tc.txPerSecond, tc.window), i)
+	}
+}
+
+func TestLRUTxCachePushTTL(t *testing.T) {
+	const ttl = 5
+	keys := testTxKeys(ttl + 2)
+	cache := NewLRUTxCache(100)
+	cache.SetPushTTL(ttl)
+
+	for _, key := range keys[:ttl] {
+		require.True(t, cache.Push(key))
+	}
+	require.True(t, cache.Has(keys[0]))
+
+	// the first key was pushed ttl pushes ago and has now expired
+	require.True(t, cache.Push(keys[ttl]))
+	require.False(t, cache.Has(keys[0]))
+	require.True(t, cache.Has(keys[1]))
+
+	// pushing a key again refreshes it
+	require.False(t, cache.Push(keys[1]))
+	require.True(t, cache.Push(keys[ttl+1]))
+	require.True(t, cache.Has(keys[1]))
+	require.False(t, cache.Has(keys[2]))
+
+	// an expired key can be admitted again
+	require.True(t, cache.Push(keys[0]))
+
+	cache.SetPushTTL(0)
+	require.True(t, cache.Has(keys[3]))
+}
+
+func TestLRUTxCachePushCount(t *testing.T) {
+	const threshold = 3
+	keys := testTxKeys(2)
+	cache := NewLRUTxCache(10)
+
+	var reached []types.TxKey
+	cache.EnablePushCounts(threshold, func(txKey types.TxKey, count int) {
+		require.Equal(t, threshold, count)
+		// the callback runs outside the lock so it may use the cache
+		require.True(t, cache.Has(txKey))
+		reached = append(reached, txKey)
+	})
+
+	cache.Push(keys[0])
+	require.Zero(t, cache.PushCount(keys[0]))
+	for i := 1; i <= 5; i++ {
+		cache.Push(keys[0])
+		require.Equal(t, i, cache.PushCount(keys[0]))
+	}
+	cache.Push(keys[1])
+	require.Zero(t, cache.PushCount(keys[1]))
+	require.Equal(t, []types.TxKey{keys[0]}, reached)
+
+	// the count is dropped with the entry
+	cache.Remove(keys[0])
+	cache.Push(keys[0])
+	require.Zero(t, cache.PushCount(keys[0]))
+}
+
+func TestSeenTxSetLastPruneDuration(t *testing.T) {
+	seenSet := NewSeenTxSet()
+	require.Zero(t, seenSet.LastPruneDuration())
+
+	for _, key := range testTxKeys(10000) {
+		seenSet.Add(key, 1)
+	}
+	seenSet.Prune(time.Now().UTC().Add(time.Second))
+	require.Zero(t, seenSet.Len())
+	require.Positive(t, seenSet.LastPruneDuration())
+}
+
+func TestSeenTxSetPruneIncremental(t *testing.T) {
+	const (
+		numTxs  = 100
+		maxScan = 30
+	)
+	keys := testTxKeys(numTxs)
+	seenSet := NewSeenTxSet()
+	for _, key := range keys[:numTxs/2] {
+		seenSet.Add(key, 1)
+	}
+	limit := time.Now().UTC().Add(time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	for _, key := range keys[numTxs/2:] {
+		seenSet.Add(key, 1)
+	}
+
+	// no call visits more than maxScan entries
+	calls := 0
+	for {
+		before := seenSet.Len()
+		more := seenSet.PruneIncremental(limit, maxScan)
+		calls++
+		require.LessOrEqual(t, before-seenSet.Len(), maxScan)
+		if !more {
+			break
+		}
+		require.Less(t, calls, numTxs)
+	}
+	require.Equal(t, (numTxs/2+maxScan-1)/maxScan, calls)
+	require.Equal(t, numTxs/2, seenSet.Len())
+	for _, key := range keys[:numTxs/2] {
+		require.False(t, seenSet.Has(key, 1))
+	}
+	for _, key := range keys[numTxs/2:] {
+		require.True(t, seenSet.Has(key, 1))
+	}
+
+	// a pass over fresh entries stops straight away
+	require.False(t, seenSet.PruneIncremental(limit, maxScan))
+	require.Equal(t, numTxs/2, seenSet.Len())
+}
+
+func TestSeenTxSetEvictOldest(t *testing.T) {
+	keys := testTxKeys(3)
+	seenSet := NewSeenTxSet()
+	require.False(t, seenSet.evictOldest())
+
+	// entries are evicted in the order they were added, even if the clock
+	// gave them all the same time
+	now := time.Now().UTC()
+	for _, key := range keys {
+		seenSet.Add(key, 1)
+		seenSet.set[key].time = now
+	}
+	seenSet.Add(keys[0], 2)
+	for i := range keys {
+		require.True(t, seenSet.evictOldest())
+		require.Nil(t, seenSet.Get(keys[i]))
+		require.Equal(t, len(keys)-i-1, seenSet.Len())
+	}
+	require.False(t, seenSet.evictOldest())
+}
+
+func TestSeenTxSetPruneIncrementalMinScan(t *testing.T) {
+	keys := testTxKeys(3)
+	seenSet := NewSeenTxSet()
+	for _, key := range keys {
+		seenSet.Add(key, 1)
+	}
+	limit := time.Now().UTC().Add(time.Hour)
+
+	// a maxScan below 1 still makes progress, one entry per call
+	calls := 0
+	for seenSet.PruneIncremental(limit, 0) {
+		calls++
+		require.Equal(t, len(keys)-calls, seenSet.Len())
+	}
+	require.Equal(t, len(keys)-1, calls)
+	require.Zero(t, seenSet.Len())
+	require.False(t, seenSet.PruneIncremental(limit, -1))
+}
+
+// FuzzCaches interprets the input as a sequence of operations on each cache
+// and checks the caches' invariants after every step. The seed corpus runs as
+// part of the regular test suite.
+func FuzzCaches(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	f.Add([]byte{0, 8, 16, 24, 32, 40, 48, 1, 9, 3, 2, 10, 4})
+	f.Add([]byte{0, 0, 0, 1, 1, 1, 2, 2, 2, 3, 3, 3, 4})
+
+	const (
+		cacheSize = 4
+		numKeys   = 8
+	)
+	keys := testTxKeys(numKeys)
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		cache := NewLRUTxCache(cacheSize)
+		seenSet := NewSeenTxSet()
+		for _, op := range ops {
+			key := keys[int(op>>3)%numKeys]
+			peer := uint16(op>>5) + 1
+			switch op % 8 {
+			case 0:
+				cache.Push(key)
+			case 1:
+				cache.Remove(key)
+			case 2:
+				seenSet.Add(key, peer)
+			case 3:
+				seenSet.Remove(key, peer)
+			case 4:
+				seenSet.RemoveKey(key)
+			case 5:
+				// prune either everything or nothing depending on the op
+				offset := time.Hour
+				if op&8 == 0 {
+					offset = -offset
+				}
+				seenSet.Prune(time.Now().UTC().Add(offset))
+			case 6:
+				cache.Reset()
+			case 7:
+				seenSet.Reset()
+			}
+
+			require.Equal(t, len(cache.cacheMap), cache.list.Len())
+			require.LessOrEqual(t, cache.list.Len(), cacheSize)
+			for e := cache.list.Front(); e != nil; e = e.Next() {
+				require.Equal(t, e, cache.cacheMap[e.Value.(*lruTxEntry).key])
+			}
+			for _, seen := range seenSet.set {
+				require.NotEmpty(t, seen.peers)
+			}
+		}
+	})
+}
+
+func TestSeenTxSetTopSeen(t *testing.T) {
+	keys := testTxKeys(4)
+	seenSet := NewSeenTxSet()
+	for i, key := range keys {
+		// key i is seen by i+1 peers
+		for peer := 1; peer <= i+1; peer++ {
+			seenSet.Add(key, uint16(peer))
+		}
+	}
+
+	require.Equal(t, []types.TxKey{keys[3], keys[2]}, seenSet.TopSeen(2))
+	require.Equal(t, []types.TxKey{keys[3], keys[2], keys[1], keys[0]}, seenSet.TopSeen(10))
+	require.Nil(t, seenSet.TopSeen(0))
+	require.Nil(t, seenSet.TopSeen(-1))
+}
+
+func TestLRUTxCacheResetInPlace(t *testing.T) {
+	keys := testTxKeys(3)
+	cache := NewLRUTxCache(10)
+	cache.SetClearInPlace(true)
+	for _, key := range keys {
+		cache.Push(key)
+	}
+	cacheMap := cache.cacheMap
+	cache.Reset()
+	require.Zero(t, cache.Len())
+	require.Empty(t, cache.cacheMap)
+	require.False(t, cache.Has(keys[0]))
+	// the same map is reused
+	cache.Push(keys[0])
+	require.Len(t, cacheMap, 1)
+}
+
+func TestSeenTxSetOnFirstSeen(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	firstSeen := make(map[types.TxKey]int)
+	seenSet.SetOnFirstSeen(func(txKey types.TxKey) {
+		// the callback runs outside the lock so it may use the set
+		require.Equal(t, 1, len(seenSet.Get(txKey)))
+		firstSeen[txKey]++
+	})
+
+	for peer := uint16(1); peer <= 3; peer++ {
+		seenSet.Add(keys[0], peer)
+		seenSet.Add(keys[1], peer)
+	}
+	require.Equal(t, map[types.TxKey]int{keys[0]: 1, keys[1]: 1}, firstSeen)
+
+	// a key seen again after being removed counts as first seen again
+	seenSet.RemoveKey(keys[0])
+	seenSet.Add(keys[0], 1)
+	require.Equal(t, 2, firstSeen[keys[0]])
+}
+
+func TestSeenTxSetPeerChurn(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[0], 3)
+	seenSet.Add(keys[1], 1)
+	require.EqualValues(t, 4, seenSet.PeerAdds())
+	require.Zero(t, seenSet.PeerRemoves())
+
+	seenSet.Remove(keys[0], 4)
+	require.Zero(t, seenSet.PeerRemoves())
+	seenSet.Remove(keys[0], 1)
+	require.EqualValues(t, 1, seenSet.PeerRemoves())
+	seenSet.RemoveKey(keys[0])
+	require.EqualValues(t, 3, seenSet.PeerRemoves())
+	seenSet.Pop(keys[1])
+	seenSet.Pop(keys[1])
+	require.EqualValues(t, 4, seenSet.PeerRemoves())
+	require.EqualValues(t, 4, seenSet.PeerAdds())
+}
+
+func TestSeenTxSetMaxPeerID(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], math.MaxUint16)
+	require.True(t, seenSet.Has(keys[0], math.MaxUint16))
+
+	seenSet.SetMaxPeerID(10)
+	seenSet.Add(keys[0], 10)
+	seenSet.Add(keys[0], 11)
+	seenSet.Add(keys[1], 500)
+	require.True(t, seenSet.Has(keys[0], 10))
+	require.False(t, seenSet.Has(keys[0], 11))
+	require.Equal(t, 1, seenSet.Len())
+	require.EqualValues(t, 2, seenSet.RejectedPeers())
+
+	seenSet.SetMaxPeerID(0)
+	seenSet.Add(keys[1], 500)
+	require.True(t, seenSet.Has(keys[1], 500))
+	require.EqualValues(t, 2, seenSet.RejectedPeers())
+}
+
+func TestSeenTxSetRemapPeer(t *testing.T) {
+	keys := testTxKeys(3)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[1], 2)
+	seenSet.Add(keys[1], 1)
+	seenSet.Add(keys[1], 5)
+	seenSet.Add(keys[2], 3)
+
+	// peer 1 announced keys[1] more recently than peer 5
+	recent := time.Now().UTC().Add(time.Minute)
+	seenSet.set[keys[1]].peers[1] = recent
+
+	require.Equal(t, 2, seenSet.RemapPeer(1, 5))
+	require.Equal(t, []uint16{5, 2}, seenSet.GetBounded(keys[0], 10))
+	require.Equal(t, []uint16{2, 5}, seenSet.GetBounded(keys[1], 10))
+	require.Equal(t, recent, seenSet.set[keys[1]].peers[5])
+	require.Equal(t, map[uint16]struct{}{3: {}}, seenSet.Get(keys[2]))
+	peer, _ := seenSet.FirstPeer(keys[0])
+	require.EqualValues(t, 5, peer)
+	require.Empty(t, seenSet.TxsSeenByPeer(1))
+
+	require.Zero(t, seenSet.RemapPeer(1, 5))
+	require.Zero(t, seenSet.RemapPeer(3, 3))
+	require.Zero(t, seenSet.RemapPeer(3, 0))
+}
+
+func TestSeenTxSetAvgPeersPerTx(t *testing.T) {
+	keys := testTxKeys(200)
+	seenSet := NewSeenTxSet()
+	require.Zero(t, seenSet.AvgPeersPerTx())
+
+	seenSet.Add(keys[0], 1)
+	require.Equal(t, 1.0, seenSet.AvgPeersPerTx())
+
+	// every tx is seen by 4 peers: the samples are 1, 2, 3 and 4 in turn, so
+	// the average converges on their mean
+	for _, key := range keys {
+		for peer := uint16(1); peer <= 4; peer++ {
+			seenSet.Add(key, peer)
+		}
+	}
+	require.InDelta(t, 2.5, seenSet.AvgPeersPerTx(), 0.1)
+
+	// a steady stream of samples of 4 converges on 4
+	for i := 0; i < 200; i++ {
+		seenSet.Add(keys[0], 4)
+	}
+	require.InDelta(t, 4, seenSet.AvgPeersPerTx(), 0.01)
+
+	// removals sample the remaining peers: 3 and 2 in turn
+	for _, key := range keys {
+		seenSet.Remove(key, 4)
+		seenSet.Remove(key, 3)
+	}
+	require.InDelta(t, 2.5, seenSet.AvgPeersPerTx(), 0.1)
+}
+
+func TestSeenTxSetPopAndRemove(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	_, ok := seenSet.PopAndRemove(keys[0])
+	require.False(t, ok)
+
+	for peer := uint16(1); peer <= 3; peer++ {
+		seenSet.Add(keys[0], peer)
+	}
+	seenSet.Add(keys[1], 1)
+	peers := seenSet.Get(keys[0])
+
+	peer, ok := seenSet.PopAndRemove(keys[0])
+	require.True(t, ok)
+	require.Contains(t, peers, peer)
+	require.Nil(t, seenSet.Get(keys[0]))
+	require.Equal(t, 1, seenSet.Len())
+	require.EqualValues(t, 3, seenSet.PeerRemoves())
+
+	// the entry is removed even if no peer can be selected
+	seenSet.SetPeerSelectable(func(uint16) bool { return false })
+	_, ok = seenSet.PopAndRemove(keys[1])
+	require.False(t, ok)
+	require.Zero(t, seenSet.Len())
+}
+
+func TestSeenTxSetFirstPeer(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	_, ok := seenSet.FirstPeer(keys[0])
+	require.False(t, ok)
+
+	seenSet.Add(keys[0], 3)
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[0], 3)
+	peer, ok := seenSet.FirstPeer(keys[0])
+	require.True(t, ok)
+	require.EqualValues(t, 3, peer)
+
+	// the first peer is still credited once it has been removed
+	seenSet.Remove(keys[0], 3)
+	seenSet.Remove(keys[0], 1)
+	peer, ok = seenSet.FirstPeer(keys[0])
+	require.True(t, ok)
+	require.EqualValues(t, 3, peer)
+
+	decoded, err := SeenTxSetFromProto(seenSet.ToProto())
+	require.NoError(t, err)
+	peer, ok = decoded.FirstPeer(keys[0])
+	require.True(t, ok)
+	require.EqualValues(t, 3, peer)
+
+	seenSet.Remove(keys[0], 2)
+	_, ok = seenSet.FirstPeer(keys[0])
+	require.False(t, ok)
+}
+
+func TestSeenTxSetScore(t *testing.T) {
+	keys := testTxKeys(4)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[1], 1)
+	seenSet.Add(keys[1], 2)
+	seenSet.Add(keys[2], 1)
+	seenSet.Add(keys[2], 2)
+
+	start := time.Now()
+	// an older entry scores lower than an equally seen recent one
+	seenSet.set[keys[2]].peers[1] = start.Add(-time.Minute)
+	seenSet.set[keys[2]].peers[2] = start.Add(-time.Minute)
+
+	fresh := seenSet.Score(keys[1], start)
+	single := seenSet.Score(keys[0], start)
+	stale := seenSet.Score(keys[2], start)
+	require.Greater(t, fresh, single)
+	require.Greater(t, single, stale)
+	require.InDelta(t, 0.5, stale, 0.01)
+	require.Zero(t, seenSet.Score(keys[3], start))
+
+	// scores decay as time passes
+	require.Less(t, seenSet.Score(keys[1], start.Add(time.Minute)), fresh)
+}
+
+func TestBucketFor(t *testing.T) {
+	keys := testTxKeys(10000)
+	prev := make([]int, len(keys))
+	for n := 1; n <= 20; n++ {
+		counts := make([]int, n)
+		moved := 0
+		for i, key := range keys {
+			bucket := BucketFor(key, n)
+			require.Equal(t, bucket, BucketFor(key, n))
+			require.GreaterOrEqual(t, bucket, 0)
+			require.Less(t, bucket, n)
+			// keys only ever move to the newly added bucket
+			if bucket != prev[i] {
+				require.Equal(t, n-1, bucket)
+				moved++
+			}
+			prev[i] = bucket
+			counts[bucket]++
+		}
+		// roughly 1/n of the keys move and buckets are roughly balanced
+		expected := len(keys) / n
+		if n > 1 {
+			require.InDelta(t, expected, moved, float64(expected)/4)
+		}
+		for _, count := range counts {
+			require.InDelta(t, expected, count, float64(expected)/4)
+		}
+	}
+	require.Panics(t, func() { BucketFor(keys[0], 0) })
+}
+
+func TestLRUTxCacheMissing(t *testing.T) {
+	keys := testTxKeys(5)
+	cache := NewLRUTxCache(10)
+	cache.Push(keys[1])
+	cache.Push(keys[3])
+
+	require.Equal(t, []types.TxKey{keys[0], keys[2], keys[4]}, cache.Missing(keys))
+	require.Empty(t, cache.Missing([]types.TxKey{keys[1], keys[3]}))
+	require.Empty(t, cache.Missing(nil))
+}
+
+func TestLRUTxCachePushLocal(t *testing.T) {
+	const size = 5
+	keys := testTxKeys(100)
+	cache := NewLRUTxCache(size)
+
+	local := keys[:2]
+	for _, key := range local {
+		require.True(t, cache.PushLocal(key))
+	}
+	for _, key := range keys[2:] {
+		cache.Push(key)
+	}
+	require.Equal(t, size, cache.Len())
+	for _, key := range local {
+		require.True(t, cache.Has(key))
+	}
+	require.True(t, cache.Has(keys[99]))
+	require.False(t, cache.Has(keys[2]))
+
+	// once only local keys remain the oldest local key is evicted
+	cache.Reset()
+	for _, key := range keys[:size] {
+		cache.PushLocal(key)
+	}
+	cache.Push(keys[size])
+	require.False(t, cache.Has(keys[0]))
+	require.True(t, cache.Has(keys[size]))
+}
+
+func TestSeenTxSetAttemptBackoff(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 1)
+	now := time.Now().UTC()
+	require.True(t, seenSet.NextAttemptAllowed(keys[0], now))
+
+	// untracked transactions are never backed off
+	seenSet.RecordAttempt(keys[1], now)
+	require.True(t, seenSet.NextAttemptAllowed(keys[1], now))
+
+	// the wait doubles with every attempt until it reaches the max
+	wait := time.Second
+	for i := 0; i < 10; i++ {
+		last := now.Add(time.Duration(i) * time.Hour)
+		seenSet.RecordAttempt(keys[0], last)
+		if i > 0 {
+			wait *= 2
+		}
+		if wait > time.Minute {
+			wait = time.Minute
+		}
+		require.False(t, seenSet.NextAttemptAllowed(keys[0], last.Add(wait-time.Millisecond)), i)
+		require.True(t, seenSet.NextAttemptAllowed(keys[0], last.Add(wait)), i)
+	}
+}
+
+func TestSeenTxSetCommonPeers(t *testing.T) {
+	keys := testTxKeys(3)
+	seenSet := NewSeenTxSet()
+	for _, peer := range []uint16{4, 1, 2, 3} {
+		seenSet.Add(keys[0], peer)
+	}
+	for _, peer := range []uint16{2, 5, 4} {
+		seenSet.Add(keys[1], peer)
+	}
+
+	require.Equal(t, []uint16{2, 4}, seenSet.CommonPeers(keys[0], keys[1]))
+	require.Equal(t, []uint16{2, 4}, seenSet.CommonPeers(keys[1], keys[0]))
+	require.Equal(t, []uint16{2, 4, 5}, seenSet.CommonPeers(keys[1], keys[1]))
+	require.Empty(t, seenSet.CommonPeers(keys[0], keys[2]))
+}
+
+func TestSeenTxSetPeersSeeingAll(t *testing.T) {
+	keys := testTxKeys(4)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[0], 3)
+	seenSet.Add(keys[1], 2)
+	seenSet.Add(keys[1], 3)
+	seenSet.Add(keys[2], 2)
+	seenSet.Add(keys[2], 1)
+
+	require.Equal(t, []uint16{2}, seenSet.PeersSeeingAll(keys[:3]))
+	require.Equal(t, []uint16{2, 3}, seenSet.PeersSeeingAll(keys[:2]))
+	require.Empty(t, seenSet.PeersSeeingAll(keys))
+	require.Empty(t, seenSet.PeersSeeingAll(nil))
+}
+
+func TestCacheHighWaterMark(t *testing.T) {
+	keys := testTxKeys(10)
+
+	cache := NewLRUTxCache(20)
+	for _, key := range keys[:6] {
+		cache.Push(key)
+	}
+	for _, key := range keys[:4] {
+		cache.Remove(key)
+	}
+	cache.Push(keys[6])
+	require.Equal(t, 3, cache.Len())
+	require.Equal(t, 6, cache.HighWaterMark())
+	cache.Reset()
+	require.Zero(t, cache.HighWaterMark())
+
+	seenSet := NewSeenTxSet()
+	for _, key := range keys[:6] {
+		seenSet.Add(key, 1)
+		seenSet.Add(key, 2)
+	}
+	for _, key := range keys[:4] {
+		seenSet.RemoveKey(key)
+	}
+	require.Equal(t, 2, seenSet.Len())
+	require.Equal(t, 6, seenSet.HighWaterMark())
+	seenSet.Reset()
+	require.Zero(t, seenSet.HighWaterMark())
+}
+
+func TestDiff(t *testing.T) {
+	keys := testTxKeys(6)
+	primary, replica := NewLRUTxCache(10), NewLRUTxCache(10)
+	for _, key := range keys[:4] {
+		primary.Push(key)
+	}
+	for _, key := range keys[2:] {
+		replica.Push(key)
+	}
+
+	onlyPrimary, onlyReplica := Diff(primary, replica)
+	require.Equal(t, keys[:2], onlyPrimary)
+	require.Equal(t, keys[4:], onlyReplica)
+	onlyReplica, onlyPrimary = Diff(replica, primary)
+	require.Equal(t, keys[:2], onlyPrimary)
+	require.Equal(t, keys[4:], onlyReplica)
+
+	onlyPrimary, onlyReplica = Diff(primary, primary)
+	require.Empty(t, onlyPrimary)
+	require.Empty(t, onlyReplica)
+
+	// keys expired by the push TTL are absent, as Has reports them
+	expiring := NewLRUTxCache(10)
+	expiring.SetPushTTL(2)
+	for _, key := range keys[:4] {
+		expiring.Push(key)
+	}
+	require.False(t, expiring.Has(keys[0]))
+	onlyExpiring, onlyPrimary := Diff(expiring, primary)
+	require.Empty(t, onlyExpiring)
+	require.Equal(t, keys[:2], onlyPrimary)
+
+	// concurrent diffs in opposite directions do not deadlock while the caches
+	// are written to
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if i%2 == 0 {
+					Diff(primary, replica)
+					primary.Push(keys[j%len(keys)])
+				} else {
+					Diff(replica, primary)
+					replica.Remove(keys[j%len(keys)])
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestLRUTxCacheSplit(t *testing.T) {
+	const (
+		size   = 50
+		shards = 4
+	)
+	keys := testTxKeys(size)
+	cache := NewLRUTxCache(size)
+	for _, key := range keys {
+		cache.Push(key)
+	}
+	// refresh the first key so that it is the most recent
+	cache.Push(keys[0])
+
+	caches := cache.Split(shards)
+	require.Len(t, caches, shards)
+	total := 0
+	for i, shard := range caches {
+		total += shard.Len()
+		var prev *lruTxEntry
+		for e := shard.list.Front(); e != nil; e = e.Next() {
+			entry := e.Value.(*lruTxEntry)
+			require.Equal(t, i, BucketFor(entry.key, shards))
+			if prev != nil {
+				// entries keep their relative recency
+				require.Less(t, prev.seq, entry.seq)
+			}
+			prev = entry
+		}
+	}
+	require.Equal(t, size, total)
+	for _, key := range keys {
+		require.True(t, caches[BucketFor(key, shards)].Has(key))
+	}
+	shard := caches[BucketFor(keys[0], shards)]
+	require.Equal(t, keys[0], shard.list.Back().Value.(*lruTxEntry).key)
+
+	require.Nil(t, cache.Split(0))
+}
+
+// TestUnsynchronizedLRUTxCache documents the supported use of an unsynchronized
+// cache: it may move between goroutines, but only with a happens-before edge
+// such as a channel handoff, never concurrently. Run under the race detector,
+// it checks that this pattern is free of data races.
+func TestUnsynchronizedLRUTxCache(t *testing.T) {
+	keys := testTxKeys(10)
+	cache := NewUnsynchronizedLRUTxCache(5)
+
+	handoff := make(chan *LRUTxCache)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c := <-handoff
+		for _, key := range keys[5:] {
+			c.Push(key)
+		}
+	}()
+
+	for _, key := range keys[:5] {
+		require.True(t, cache.Push(key))
+	}
+	handoff <- cache
+	<-done
+
+	require.Equal(t, 5, cache.Len())
+	require.False(t, cache.Has(keys[0]))
+	require.True(t, cache.Has(keys[9]))
+	cache.Remove(keys[9])
+	require.False(t, cache.Has(keys[9]))
+}
+
+func TestLRUTxCacheHotKeys(t *testing.T) {
+	keys := testTxKeys(4)
+	now := time.Unix(1_000_000, 0)
+	cache := NewLRUTxCache(10)
+	cache.now = func() time.Time { return now }
+	require.Nil(t, cache.HotKeys())
+
+	cache.EnableHotKeys(5, time.Second, 2)
+	for i := 0; i < 10; i++ {
+		cache.Push(keys[0])
+		cache.Has(keys[0])
+	}
+	cache.Push(keys[1])
+	cache.Has(keys[1])
+	require.Equal(t, []types.TxKey{keys[0]}, cache.HotKeys())
+
+	// ops spread over several windows never reach the threshold
+	for i := 0; i < 8; i++ {
+		cache.Has(keys[2])
+		now = now.Add(500 * time.Millisecond)
+	}
+	require.Equal(t, []types.TxKey{keys[0]}, cache.HotKeys())
+
+	// the hot list is bounded, dropping the oldest key
+	for _, key := range keys[1:] {
+		for i := 0; i < 5; i++ {
+			cache.Has(key)
+		}
+	}
+	require.Equal(t, []types.TxKey{keys[2], keys[3]}, cache.HotKeys())
+
+	cache.EnableHotKeys(0, time.Second, 2)
+	require.Nil(t, cache.HotKeys())
+}
+
+func TestLRUTxCacheCanEvict(t *testing.T) {
+	keys := testTxKeys(10)
+	pinned := keys[0]
+	cache := NewLRUTxCache(3)
+	cache.SetCanEvict(func(txKey types.TxKey) bool { return txKey != pinned })
+
+	// the pinned key survives cycling every other key through the cache
+	for _, key := range keys {
+		require.True(t, cache.Push(key))
+		require.True(t, cache.Has(pinned))
+	}
+	require.Equal(t, 3, cache.Len())
+	require.True(t, cache.Has(keys[9]))
+
+	// once every key is vetoed new keys are rejected
+	cache.SetCanEvict(func(types.TxKey) bool { return false })
+	require.False(t, cache.Push(types.Tx("rejected").Key()))
+	_, ok := cache.NextVictim()
+	require.False(t, ok)
+
+	cache.SetCanEvict(nil)
+	require.True(t, cache.Push(types.Tx("admitted").Key()))
+	require.False(t, cache.Has(pinned))
+}
+
+func TestLRUTxCacheNextVictim(t *testing.T) {
+	keys := testTxKeys(6)
+	cache := NewLRUTxCache(3)
+	_, ok := cache.NextVictim()
+	require.False(t, ok)
+
+	cache.PushLocal(keys[0])
+	cache.Push(keys[1])
+	cache.Push(keys[2])
+	for _, key := range keys[3:] {
+		victim, ok := cache.NextVictim()
+		require.True(t, ok)
+		require.True(t, cache.Has(victim))
+		require.NotEqual(t, keys[0], victim, "local keys are evicted last")
+
+		cache.Push(key)
+		require.False(t, cache.Has(victim))
+	}
+	// nothing is evicted by peeking
+	require.Equal(t, 3, cache.Len())
+}
+
+func TestLRUTxCacheMinRetention(t *testing.T) {
+	keys := testTxKeys(10)
+	now := time.Unix(1_000_000, 0)
+	cache := NewLRUTxCache(3)
+	cache.now = func() time.Time { return now }
+	cache.SetMinRetention(time.Minute)
+
+	cache.Push(keys[0])
+	now = now.Add(time.Minute)
+	cache.Push(keys[1])
+	cache.Push(keys[2])
+
+	// only the key older than the retention can be evicted
+	require.True(t, cache.Push(keys[3]))
+	require.False(t, cache.Has(keys[0]))
+
+	// a burst cannot evict the protected keys and is rejected instead
+	for _, key := range keys[4:] {
+		require.False(t, cache.Push(key))
+		require.False(t, cache.Has(key))
+	}
+	require.ErrorIs(t, cache.TryPush(keys[4]), ErrCacheFull)
+	require.False(t, cache.CompareAndPush(keys[4]))
+	require.False(t, cache.PushLocal(keys[4]))
+	require.False(t, cache.PushWithSender(keys[4], "sender"))
+	for _, key := range keys[1:4] {
+		require.True(t, cache.Has(key))
+	}
+
+	// once the protection lapses the oldest key is evicted again
+	now = now.Add(time.Minute)
+	require.True(t, cache.Push(keys[4]))
+	require.False(t, cache.Has(keys[1]))
+
+	cache.SetMinRetention(0)
+	require.True(t, cache.Push(keys[5]))
+	require.Equal(t, 3, cache.Len())
+}
+
+func TestLRUTxCacheCoverageOf(t *testing.T) {
+	keys := testTxKeys(6)
+	cache := NewLRUTxCache(3)
+	for _, key := range keys[:4] {
+		cache.Push(key)
+	}
+
+	present, total := cache.CoverageOf(keys)
+	require.Equal(t, 3, present)
+	require.Equal(t, 6, total)
+	require.Equal(t, LRUTxCacheStats{Inserts: 4, Evictions: 1}, cache.Stats())
+
+	present, total = cache.CoverageOf(nil)
+	require.Zero(t, present)
+	require.Zero(t, total)
+}
+
+func TestLRUTxCacheResetStats(t *testing.T) {
+	keys := testTxKeys(4)
+	cache := NewLRUTxCache(3)
+	for _, key := range keys {
+		cache.Push(key)
+	}
+	cache.Has(keys[0])
+	cache.Has(keys[3])
+	cache.Has(keys[3])
+	require.Equal(t, LRUTxCacheStats{Hits: 2, Misses: 1, Inserts: 4, Evictions: 1}, cache.Stats())
+	require.NotZero(t, cache.EvictionRate(time.Second))
+
+	cache.ResetStats()
+	require.Equal(t, LRUTxCacheStats{}, cache.Stats())
+	require.Zero(t, cache.EvictionRate(time.Second))
+	require.Equal(t, 3, cache.Len())
+	for _, key := range keys[1:] {
+		require.True(t, cache.Has(key))
+	}
+	require.Equal(t, LRUTxCacheStats{Hits: 3}, cache.Stats())
+}
+
+func TestLRUTxCacheChurn(t *testing.T) {
+	keys := testTxKeys(10)
+	cache := NewLRUTxCache(4)
+	require.Zero(t, cache.Churn())
+
+	// filling the cache evicts nothing, nor do repeated or removed keys
+	for _, key := range keys[:4] {
+		cache.Push(key)
+	}
+	cache.Push(keys[0])
+	cache.Remove(keys[1])
+	require.Zero(t, cache.Churn())
+
+	// one key fills the freed slot, the next four each evict
+	for _, key := range keys[4:9] {
+		cache.Push(key)
+	}
+	require.InDelta(t, 4.0/9, cache.Churn(), 1e-9)
+
+	cache.ResetStats()
+	require.Zero(t, cache.Churn())
+	cache.Push(keys[9])
+	require.Equal(t, 1.0, cache.Churn())
+}
+
+func TestLRUTxCachePauseEviction(t *testing.T) {
+	keys := testTxKeys(10)
+	cache := NewLRUTxCache(4)
+	for _, key := range keys[:4] {
+		cache.Push(key)
+	}
+
+	cache.PauseEviction()
+	for _, key := range keys[4:] {
+		require.True(t, cache.Push(key))
+	}
+	require.Equal(t, 10, cache.Len())
+	require.Zero(t, cache.Stats().Evictions)
+
+	// resuming trims the oldest keys until the cache is back within capacity
+	cache.ResumeEviction()
+	require.Equal(t, keys[6:], cache.OrderedKeys())
+	require.EqualValues(t, 6, cache.Stats().Evictions)
+
+	cache.Push(keys[0])
+	require.Equal(t, 4, cache.Len())
+	require.False(t, cache.Has(keys[6]))
+}
+
+func TestLRUTxCacheEvictOldest(t *testing.T) {
+	keys := testTxKeys(6)
+	cache := NewLRUTxCache(5)
+	require.Nil(t, cache.EvictOldest(2))
+	for _, key := range keys[:5] {
+		cache.Push(key)
+	}
+	// a pushed again key moves to the back
+	cache.Push(keys[0])
+
+	require.Equal(t, []types.TxKey{keys[1], keys[2], keys[3]}, cache.EvictOldest(3))
+	require.Equal(t, []types.TxKey{keys[4], keys[0]}, cache.OrderedKeys())
+	require.EqualValues(t, 3, cache.Stats().Evictions)
+
+	require.Nil(t, cache.EvictOldest(0))
+	require.Equal(t, []types.TxKey{keys[4], keys[0]}, cache.EvictOldest(10))
+	require.Zero(t, cache.Len())
+}
+
+func TestLRUTxCacheEvictionRate(t *testing.T) {
+	const size = 10
+	keys := testTxKeys(100)
+	now := time.Unix(1_000_000, 0)
+	cache := NewLRUTxCache(size)
+	cache.now = func() time.Time { return now }
+
+	// filling the cache does not evict
+	for _, key := range keys[:size] {
+		cache.Push(key)
+	}
+	require.Zero(t, cache.EvictionRate(10*time.Second))
+
+	// 5 evictions per second for 4 seconds
+	next := size
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Second)
+		for j := 0; j < 5; j++ {
+			cache.Push(keys[next])
+			next++
+		}
+	}
+	require.Equal(t, 5.0, cache.EvictionRate(time.Second))
+	require.Equal(t, 2.0, cache.EvictionRate(10*time.Second))
+
+	// evictions age out of the window
+	now = now.Add(6 * time.Second)
+	require.Zero(t, cache.EvictionRate(5*time.Second))
+	require.Equal(t, 1.0, cache.EvictionRate(20*time.Second))
+	now = now.Add(time.Hour)
+	require.Zero(t, cache.EvictionRate(time.Hour))
+}
+
+func TestLRUTxCacheWarmupProgress(t *testing.T) {
+	keys := testTxKeys(10)
+	cache := NewLRUTxCache(8)
+	require.Equal(t, 1.0, cache.WarmupProgress())
+
+	cache.Seed(keys[:2], 5)
+	require.InDelta(t, 0.4, cache.WarmupProgress(), 1e-9)
+	// pushes after seeding count towards the warmup
+	cache.Push(keys[2])
+	require.InDelta(t, 0.6, cache.WarmupProgress(), 1e-9)
+	cache.Seed(keys[3:6], 5)
+	require.Equal(t, 1.0, cache.WarmupProgress())
+
+	// the target is capped at the cache's size
+	cache.Reset()
+	cache.Seed(keys, 20)
+	require.Equal(t, 1.0, cache.WarmupProgress())
+	require.Equal(t, 8, cache.Len())
+}
+
+func TestLRUTxCacheOrderedKeys(t *testing.T) {
+	keys := testTxKeys(5)
+	cache := NewLRUTxCache(4)
+	require.Empty(t, cache.OrderedKeys())
+
+	for _, key := range keys[:4] {
+		cache.Push(key)
+	}
+	require.True(t, cache.Touch(keys[0]))
+	require.False(t, cache.Touch(keys[4]))
+	cache.Push(keys[1])
+	cache.Remove(keys[2])
+	require.Equal(t, []types.TxKey{keys[3], keys[0], keys[1]}, cache.OrderedKeys())
+
+	// the touched key is no longer the next to be evicted
+	cache.Push(keys[4])
+	cache.Push(keys[2])
+	require.Equal(t, []types.TxKey{keys[0], keys[1], keys[4], keys[2]}, cache.OrderedKeys())
+}
+
+func TestLRUTxCacheSnapshot(t *testing.T) {
+	keys := testTxKeys(200)
+	cache := NewLRUTxCache(100)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i, key := range keys {
+			if i%3 == 0 {
+				cache.Remove(keys[i/2])
+			}
+			cache.Push(key)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			n, snapshot := cache.Snapshot()
+			require.Len(t, snapshot, n)
+			require.Equal(t, keys[len(keys)-1], snapshot[n-1])
+			return
+		default:
+			n, snapshot := cache.Snapshot()
+			require.Len(t, snapshot, n)
+		}
+	}
+}
+
+func TestLRUTxCacheSenderDiversity(t *testing.T) {
+	const size = 10
+	keys := testTxKeys(30)
+	cache := NewLRUTxCache(size)
+	cache.SetMaxSenderFraction(0.3)
+
+	for _, key := range keys[:2] {
+		cache.PushWithSender(key, "bob")
+	}
+	for _, key := range keys[2:4] {
+		cache.PushWithSender(key, "carol")
+	}
+	// alice floods the cache but is capped at 3 keys
+	for _, key := range keys[4:24] {
+		cache.PushWithSender(key, "alice")
+	}
+	require.Equal(t, 7, cache.Len())
+	require.Equal(t, 3, cache.senderCounts["alice"])
+	for _, key := range keys[:4] {
+		require.True(t, cache.Has(key))
+	}
+	for _, key := range keys[21:24] {
+		require.True(t, cache.Has(key))
+	}
+
+	// when full, the sender with the most keys is evicted from first even
+	// though older keys from other senders exist
+	for _, key := range keys[24:27] {
+		cache.Push(key)
+	}
+	require.Equal(t, size, cache.Len())
+	cache.PushWithSender(keys[27], "dave")
+	require.Equal(t, size, cache.Len())
+	require.False(t, cache.Has(keys[21]))
+	require.Equal(t, 2, cache.senderCounts["alice"])
+	for _, key := range keys[:4] {
+		require.True(t, cache.Has(key))
+	}
+}
+
+func TestLRUTxCacheSenderCapNotEvictable(t *testing.T) {
+	keys := testTxKeys(4)
+	cache := NewLRUTxCache(10)
+	cache.SetMaxSenderFraction(0.2)
+	require.True(t, cache.PushWithSender(keys[0], "alice"))
+	require.True(t, cache.PushWithSender(keys[1], "alice"))
+
+	// alice is at the cap but none of alice's keys may be evicted
+	cache.SetCanEvict(func(types.TxKey) bool { return false })
+	require.False(t, cache.PushWithSender(keys[2], "alice"))
+	require.False(t, cache.Has(keys[2]))
+	require.Equal(t, 2, cache.senderCounts["alice"])
+	cache.SetCanEvict(nil)
+
+	cache.PauseEviction()
+	require.False(t, cache.PushWithSender(keys[2], "alice"))
+	require.Equal(t, 2, cache.senderCounts["alice"])
+	// other senders are unaffected
+	require.True(t, cache.PushWithSender(keys[3], "bob"))
+	cache.ResumeEviction()
+
+	require.True(t, cache.PushWithSender(keys[2], "alice"))
+	require.False(t, cache.Has(keys[0]))
+	require.Equal(t, 2, cache.senderCounts["alice"])
+}
+
+func TestSeenTxSetGetBounded(t *testing.T) {
+	txKey := types.Tx("tx1").Key()
+	seenSet := NewSeenTxSet()
+	require.Nil(t, seenSet.GetBounded(txKey, 2))
+
+	for _, peer := range []uint16{5, 3, 9, 1} {
+		seenSet.Add(txKey, peer)
+	}
+	seenSet.Add(txKey, 3)
+	require.Equal(t, []uint16{5, 3}, seenSet.GetBounded(txKey, 2))
+	require.Equal(t, []uint16{5, 3, 9, 1}, seenSet.GetBounded(txKey, 10))
+
+	seenSet.Remove(txKey, 3)
+	require.Equal(t, []uint16{5, 9, 1}, seenSet.GetBounded(txKey, 10))
+	require.Empty(t, seenSet.GetBounded(txKey, 0))
+	require.Empty(t, seenSet.GetBounded(txKey, -1))
+}
+
+func TestSeenTxSetPopSkipsUnselectablePeers(t *testing.T) {
+	keys := testTxKeys(2)
+	blacklisted := map[uint16]bool{1: true}
+	seenSet := NewSeenTxSet()
+	seenSet.SetPeerSelectable(func(peer uint16) bool { return !blacklisted[peer] })
+
+	seenSet.Add(keys[0], 1)
+	require.Zero(t, seenSet.Pop(keys[0]))
+	require.True(t, seenSet.Has(keys[0], 1))
+
+	seenSet.Add(keys[1], 1)
+	seenSet.Add(keys[1], 2)
+	require.Equal(t, uint16(2), seenSet.Pop(keys[1]))
+	require.Zero(t, seenSet.Pop(keys[1]))
+
+	seenSet.SetPeerSelectable(nil)
+	require.Equal(t, uint16(1), seenSet.Pop(keys[0]))
+}
+
+func TestSeenTxSetSinglySeen(t *testing.T) {
+	keys := testTxKeys(4)
+	seenSet := NewSeenTxSet()
+	require.Empty(t, seenSet.SinglySeen())
+
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[1], 1)
+	seenSet.Add(keys[1], 2)
+	seenSet.Add(keys[2], 3)
+	seenSet.Add(keys[3], 1)
+	seenSet.Add(keys[3], 2)
+	seenSet.Add(keys[3], 3)
+	require.ElementsMatch(t, []types.TxKey{keys[0], keys[2]}, seenSet.SinglySeen())
+
+	// an entry left with one peer becomes fragile
+	seenSet.Remove(keys[1], 2)
+	require.ElementsMatch(t, []types.TxKey{keys[0], keys[1], keys[2]}, seenSet.SinglySeen())
+}
+
+func TestSeenTxSetPruneJitter(t *testing.T) {
+	const ttl = time.Hour
+	now := time.Now().UTC()
+	limit := now.Add(-ttl)
+	seenSet := NewSeenTxSet()
+	require.Equal(t, limit, seenSet.pruneLimit(limit, now))
+
+	seenSet.SetPruneJitter(0.1)
+	limits := make(map[time.Time]struct{})
+	for i := 0; i < 100; i++ {
+		jittered := seenSet.pruneLimit(limit, now)
+		require.WithinDuration(t, limit, jittered, ttl/10)
+		limits[jittered] = struct{}{}
+	}
+	require.Greater(t, len(limits), 1)
+	// a limit in the future implies no TTL to jitter
+	require.Equal(t, now.Add(time.Minute), seenSet.pruneLimit(now.Add(time.Minute), now))
+
+	// entries well outside the band are unaffected
+	keys := testTxKeys(2)
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[1], 1)
+	seenSet.set[keys[0]].time = now.Add(-2 * ttl)
+	seenSet.Prune(time.Now().UTC().Add(-ttl))
+	require.Equal(t, 1, seenSet.Len())
+	require.True(t, seenSet.Has(keys[1], 1))
+}
+
+func TestSeenTxSetPruneAndCollect(t *testing.T) {
+	keys := testTxKeys(5)
+	seenSet := NewSeenTxSet()
+	now := time.Now().UTC()
+	for i, key := range keys {
+		seenSet.Add(key, 1)
+		seenSet.set[key].time = now.Add(time.Duration(i-3) * time.Minute)
+	}
+
+	require.ElementsMatch(t, keys[:3], seenSet.PruneAndCollect(now.Add(-time.Second)))
+	require.Equal(t, 2, seenSet.Len())
+	for i, key := range keys {
+		require.Equal(t, i >= 3, seenSet.Has(key, 1), i)
+	}
+	require.Empty(t, seenSet.PruneAndCollect(now.Add(-time.Second)))
+}
+
+func TestSeenTxSetPruneRange(t *testing.T) {
+	keys := testTxKeys(4)
+	seenSet := NewSeenTxSet()
+	start := time.Now().UTC()
+	for i, key := range keys {
+		seenSet.Add(key, 1)
+		seenSet.set[key].time = start.Add(time.Duration(i) * time.Minute)
+	}
+
+	require.Equal(t, 2, seenSet.PruneRange(start.Add(time.Minute), start.Add(3*time.Minute)))
+	require.Equal(t, 2, seenSet.Len())
+	require.True(t, seenSet.Has(keys[0], 1))
+	require.False(t, seenSet.Has(keys[1], 1))
+	require.False(t, seenSet.Has(keys[2], 1))
+	require.True(t, seenSet.Has(keys[3], 1))
+
+	require.Zero(t, seenSet.PruneRange(start.Add(time.Hour), start.Add(2*time.Hour)))
+	require.Equal(t, 2, seenSet.Len())
+}
+
+func TestSeenTxSetAddDigest(t *testing.T) {
+	keys := testTxKeys(6)
+	have := make(map[types.TxKey]bool)
+	for _, key := range keys[:3] {
+		have[key] = true
+	}
+	seenSet := NewSeenTxSet()
+	var firstSeen []types.TxKey
+	seenSet.SetOnFirstSeen(func(txKey types.TxKey) { firstSeen = append(firstSeen, txKey) })
+	seenSet.Add(keys[3], 2)
+
+	require.Equal(t, 3, seenSet.AddDigest(1, keys, func(txKey types.TxKey) bool { return have[txKey] }))
+	for i, key := range keys {
+		require.Equal(t, i >= 3, seenSet.Has(key, 1), i)
+	}
+	require.Equal(t, []types.TxKey{keys[3], keys[4], keys[5]}, firstSeen)
+
+	// without a filter every key is recorded, and peer 0 never is
+	require.Equal(t, 6, seenSet.AddDigest(2, keys, nil))
+	require.Zero(t, seenSet.AddDigest(0, keys, nil))
+	require.Equal(t, 6, seenSet.Len())
+}
+
+func TestSeenTxSetPruneEmpty(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 1)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[1], 1)
+	require.Zero(t, seenSet.PruneEmpty())
+
+	// popping every peer leaves the entry behind with an empty peer set
+	for seenSet.Pop(keys[0]) != 0 {
+	}
+	require.Equal(t, 2, seenSet.Len())
+	require.Empty(t, seenSet.Get(keys[0]))
+
+	require.Equal(t, 1, seenSet.PruneEmpty())
+	require.Equal(t, 1, seenSet.Len())
+	require.Nil(t, seenSet.Get(keys[0]))
+	require.True(t, seenSet.Has(keys[1], 1))
+}
+
+func TestSeenTxSetPrunePeers(t *testing.T) {
+	keys := testTxKeys(2)
+	seenSet := NewSeenTxSet()
+	for peer := uint16(1); peer <= 3; peer++ {
+		seenSet.Add(keys[0], peer)
+	}
+	seenSet.Add(keys[1], 1)
+
+	// peers 1 and 2 last announced the txs an hour ago
+	stale := time.Now().UTC().Add(-time.Hour)
+	seenSet.set[keys[0]].peers[1] = stale
+	seenSet.set[keys[0]].peers[2] = stale
+	seenSet.set[keys[1]].peers[1] = stale
+
+	seenSet.PrunePeers(time.Now().UTC().Add(-time.Minute))
+	require.Equal(t, 1, seenSet.Len())
+	require.Equal(t, map[uint16]struct{}{3: {}}, seenSet.Get(keys[0]))
+	require.Equal(t, []uint16{3}, seenSet.GetBounded(keys[0], 10))
+	require.Nil(t, seenSet.Get(keys[1]))
+
+	// announcing again refreshes the peer's timestamp
+	seenSet.set[keys[0]].peers[3] = stale
+	seenSet.Add(keys[0], 3)
+	seenSet.PrunePeers(time.Now().UTC().Add(-time.Minute))
+	require.True(t, seenSet.Has(keys[0], 3))
+}
+
+func TestSeenTxSetProtoRoundTrip(t *testing.T) {
+	keys := testTxKeys(3)
+	seenSet := NewSeenTxSet()
+	seenSet.Add(keys[0], 4)
+	seenSet.Add(keys[0], 2)
+	seenSet.Add(keys[1], 1)
+	seenSet.Add(keys[2], 3)
+	seenSet.Add(keys[2], 1)
+
+	bz, err := seenSet.ToProto().Marshal()
+	require.NoError(t, err)
+	var pb protomem.SeenTxSet
+	require.NoError(t, pb.Unmarshal(bz))
+	decoded, err := SeenTxSetFromProto(&pb)
+	require.NoError(t, err)
+
+	require.Equal(t, seenSet.set, decoded.set)
+	require.Equal(t, []uint16{4, 2}, decoded.GetBounded(keys[0], 10))
+	require.Equal(t, seenSet.ToProto(), decoded.ToProto())
+
+	pb.Entries[0].Peers[0].Peer = 0
+	_, err = SeenTxSetFromProto(&pb)
+	require.Error(t, err)
+	pb.Entries[0].TxKey = []byte("short")
+	_, err = SeenTxSetFromProto(&pb)
+	require.Error(t, err)
+}
+
+func TestLRUTxCacheAdaptiveSizing(t *testing.T) {
+	require.ErrorIs(t, NewLRUTxCache(0).EnableAdaptiveSizing(1, 10, 0.5, 10), ErrDisabled)
+	cache := NewLRUTxCache(10)
+	require.ErrorIs(t, cache.EnableAdaptiveSizing(0, 10, 0.5, 10), ErrInvalidSize)
+	require.ErrorIs(t, cache.EnableAdaptiveSizing(10, 5, 0.5, 10), ErrInvalidSize)
+	_, _, _, _, ok := cache.AdaptiveSizing()
+	require.False(t, ok)
+
+	require.NoError(t, cache.EnableAdaptiveSizing(10, 100, 0.5, 10))
+	size, minSize, maxSize, target, ok := cache.AdaptiveSizing()
+	require.True(t, ok)
+	require.Equal(t, []int{10, 10, 100}, []int{size, minSize, maxSize})
+	require.Equal(t, 0.5, target)
+
+	// every push is a miss so the cache grows until it reaches the max
+	keys := testTxKeys(200)
+	for _, key := range keys[:40] {
+		cache.Push(key)
+	}
+	size, _, _, _, _ = cache.AdaptiveSizing()
+	require.Equal(t, 100, size)
+	for _, key := range keys[40:] {
+		cache.Push(key)
+	}
+	size, _, _, _, _ = cache.AdaptiveSizing()
+	require.Equal(t, 100, size)
+	require.Equal(t, 100, cache.Len())
+
+	// every push is a hit so the cache shrinks back to the min
+	for i := 0; i < 40; i++ {
+		cache.Push(keys[len(keys)-1])
+	}
+	size, _, _, _, _ = cache.AdaptiveSizing()
+	require.Equal(t, 10, size)
+	require.Equal(t, 10, cache.Len())
+	require.True(t, cache.Has(keys[len(keys)-1]))
+}
+
+func TestLRUTxCacheAdaptiveHysteresis(t *testing.T) {
+	const interval = 10
+	cache := NewLRUTxCache(20)
+	require.ErrorIs(t, cache.SetAdaptiveHysteresis(2, 1), ErrDisabled)
+	_, _, ok := cache.AdaptiveHysteresis()
+	require.False(t, ok)
+
+	keys := testTxKeys(200)
+	hot, next := keys[0], 1
+	cache.Push(hot)
+	require.NoError(t, cache.EnableAdaptiveSizing(20, 80, 0.5, interval))
+	sustain, cooldown, ok := cache.AdaptiveHysteresis()
+	require.True(t, ok)
+	require.Equal(t, []int{1, 0}, []int{sustain, cooldown})
+	require.ErrorIs(t, cache.SetAdaptiveHysteresis(0, 0), ErrInvalidSize)
+	require.ErrorIs(t, cache.SetAdaptiveHysteresis(1, -1), ErrInvalidSize)
+
+	// evaluate drives one evaluation interval of all hits or all misses and
+	// returns the resulting size
+	evaluate := func(hit bool) int {
+		for i := 0; i < interval; i++ {
+			if hit {
+				cache.Push(hot)
+			} else {
+				cache.Push(keys[next])
+				next++
+			}
+		}
+		size, _, _, _, _ := cache.AdaptiveSizing()
+		return size
+	}
+
+	// without hysteresis an oscillating hit rate makes the size flap
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 20, evaluate(true))
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 20, evaluate(true))
+
+	require.NoError(t, cache.SetAdaptiveHysteresis(3, 2))
+	for i := 0; i < 4; i++ {
+		require.Equal(t, 20, evaluate(false))
+		require.Equal(t, 20, evaluate(true))
+	}
+
+	// a sustained low hit rate grows the cache, then the cooldown holds it
+	require.Equal(t, 20, evaluate(false))
+	require.Equal(t, 20, evaluate(false))
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 40, evaluate(false))
+	require.Equal(t, 80, evaluate(false))
+}
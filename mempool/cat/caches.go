@@ -0,0 +1,244 @@
+package cat
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	tmsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/types"
+)
+
+// TxCacheStatus describes where a transaction key is currently known.
+type TxCacheStatus struct {
+	// InDedup reports whether the key is in the dedup cache
+	InDedup bool
+	// SeenByPeers is the number of peers that have announced the key
+	SeenByPeers int
+}
+
+// LookupTx reports the status of the key across the dedup cache and the set of
+// transactions seen by peers. It is intended for diagnostics.
+func LookupTx(dedup *LRUTxCache, seen *SeenTxSet, txKey types.TxKey) TxCacheStatus {
+	return TxCacheStatus{
+		InDedup:     dedup.holds(txKey),
+		SeenByPeers: len(seen.Get(txKey)),
+	}
+}
+
+// WriteReport writes a human readable summary of the dedup cache and the set of
+// transactions seen by peers, intended to be attached to incident reports.
+func WriteReport(w io.Writer, dedup *LRUTxCache, seen *SeenTxSet) error {
+	dedup.rlock()
+	dedupLen, capacity := dedup.list.Len(), dedup.effectiveCapacity()
+	dedup.runlock()
+	var fill float64
+	if capacity > 0 {
+		fill = 100 * float64(dedupLen) / float64(capacity)
+	}
+	seenLen := seen.Len()
+	peers, oldest := seen.summary()
+	var oldestAge time.Duration
+	if !oldest.IsZero() {
+		oldestAge = time.Since(oldest)
+	}
+
+	_, err := fmt.Fprintf(w,
+		"dedup cache\n"+
+			"  fill: %d/%d (%.1f%%)\n"+
+			"seen tx set\n"+
+			"  entries: %d\n"+
+			"  peers tracked: %d\n"+
+			"  oldest entry age: %s\n",
+		dedupLen, capacity, fill,
+		seenLen, peers, oldestAge,
+	)
+	return err
+}
+
+// TxCaches coordinates the dedup cache and the set of transactions seen by
+// peers so that operations spanning both happen as one logical step.
+//
+// A global entry budget can be set to bound the combined number of entries
+// tracked by both caches. When the budget is exceeded, entries are evicted
+// from the least critical cache first: the seen set (which only helps with
+// routing requests) before the dedup cache (which protects against
+// reprocessing transactions).
+type TxCaches struct {
+	mtx    tmsync.Mutex
+	logger log.Logger
+	budget int
+
+	dedup *LRUTxCache
+	seen  *SeenTxSet
+	// sent optionally records the peers transactions were forwarded to
+	sent *SentTxSet
+}
+
+// NewTxCaches returns a coordinator over the given caches. A budget of 0
+// means the combined number of entries is not bounded.
+func NewTxCaches(dedup *LRUTxCache, seen *SeenTxSet, budget int) *TxCaches {
+	return &TxCaches{
+		logger: log.NewNopLogger(),
+		budget: budget,
+		dedup:  dedup,
+		seen:   seen,
+	}
+}
+
+// SetLogger sets the logger used to report inconsistencies between the caches.
+func (c *TxCaches) SetLogger(logger log.Logger) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.logger = logger
+}
+
+// SetSentTxSet sets the set of peers transactions were forwarded to, which
+// ShouldSend consults along with the seen set.
+func (c *TxCaches) SetSentTxSet(sent *SentTxSet) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.sent = sent
+}
+
+// ShouldSend reports whether the transaction should be gossiped to the peer:
+// only if the peer has not announced it to us and we have not already sent it
+// to them.
+func (c *TxCaches) ShouldSend(txKey types.TxKey, peer uint16) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.seen.Has(txKey, peer) {
+		return false
+	}
+	return c.sent == nil || !c.sent.Has(txKey, peer)
+}
+
+// Reconcile corrects drift between the caches and the authoritative set of
+// transactions currently in the pool. Transactions in the pool have already
+// been received, so any seen entries for them are stale and are removed. It
+// returns the number of inconsistencies that were corrected.
+func (c *TxCaches) Reconcile(present map[types.TxKey]bool) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	corrected := 0
+	for txKey, isPresent := range present {
+		if isPresent && c.seen.RemoveKeyReported(txKey) {
+			c.logger.Info("removed seen entry for tx already in the pool", "txKey", txKey)
+			corrected++
+		}
+	}
+	return corrected
+}
+
+// ResetAll empties every cache as one operation. All caches are locked before
+// any is reset so that no caller can observe a partially reset state.
+func (c *TxCaches) ResetAll() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.dedup.lock()
+	defer c.dedup.unlock()
+	c.seen.mtx.Lock()
+	defer c.seen.mtx.Unlock()
+
+	c.dedup.reset()
+	c.seen.reset()
+}
+
+// Push adds the key to the dedup cache, enforcing the global budget.
+func (c *TxCaches) Push(txKey types.TxKey) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	added := c.dedup.Push(txKey)
+	c.enforceBudget()
+	return added
+}
+
+// Add records that the peer has seen the key, enforcing the global budget.
+// Keys already in the dedup cache have been processed, so there is no point
+// tracking who has seen them and they are skipped.
+func (c *TxCaches) Add(txKey types.TxKey, peer uint16) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.dedup.holds(txKey) {
+		return
+	}
+	c.seen.Add(txKey, peer)
+	c.enforceBudget()
+}
+
+// Total returns the combined number of entries across all caches.
+func (c *TxCaches) Total() int {
+	return c.dedup.Len() + c.seen.Len()
+}
+
+// Budget returns the maximum combined number of entries. 0 means unbounded.
+func (c *TxCaches) Budget() int {
+	return c.budget
+}
+
+// enforceBudget evicts entries until the combined total is within the budget.
+// This assumes that the coordinator's mutex is already locked.
+func (c *TxCaches) enforceBudget() {
+	if c.budget == 0 {
+		return
+	}
+	for c.Total() > c.budget {
+		if c.seen.evictOldest() {
+			continue
+		}
+		if !c.dedup.evictOldest() {
+			return
+		}
+	}
+}
+
+// CacheOp is an operation recorded by a cache's operation log.
+type CacheOp struct {
+	// Op names the operation, e.g. "push" or "pop"
+	Op  string
+	Key types.TxKey
+	// Result is the outcome of the operation: whether a key was added, found
+	// or removed, or a peer was popped
+	Result bool
+	Time   time.Time
+}
+
+// opLog is a fixed size ring buffer of the most recent operations of a cache.
+// It is not thread safe and relies on the owning cache's mutex.
+type opLog struct {
+	ops  []CacheOp
+	next int
+	full bool
+}
+
+func newOpLog(size int) *opLog {
+	return &opLog{ops: make([]CacheOp, size)}
+}
+
+// record appends an operation, overwriting the oldest one once full. It is a
+// no-op on a nil log so that callers need not check whether logging is on.
+func (l *opLog) record(op string, txKey types.TxKey, result bool) {
+	if l == nil {
+		return
+	}
+	l.ops[l.next] = CacheOp{Op: op, Key: txKey, Result: result, Time: time.Now()}
+	l.next++
+	if l.next == len(l.ops) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// recent returns a copy of the recorded operations, oldest first.
+func (l *opLog) recent() []CacheOp {
+	if l == nil {
+		return nil
+	}
+	if !l.full {
+		return append([]CacheOp(nil), l.ops[:l.next]...)
+	}
+	ops := make([]CacheOp, 0, len(l.ops))
+	ops = append(ops, l.ops[l.next:]...)
+	return append(ops, l.ops[:l.next]...)
+}
@@ -0,0 +1,253 @@
+package cat
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+func testTxKeys(n int) []types.TxKey {
+	keys := make([]types.TxKey, n)
+	for i := range keys {
+		keys[i] = types.Tx([]byte(fmt.Sprintf("tx%d", i))).Key()
+	}
+	return keys
+}
+
+func TestTxCachesBudget(t *testing.T) {
+	const budget = 5
+	keys := testTxKeys(10)
+	caches := NewTxCaches(NewLRUTxCache(10), NewSeenTxSet(), budget)
+	require.Equal(t, budget, caches.Budget())
+
+	for _, key := range keys[:3] {
+		caches.Push(key)
+	}
+	for _, key := range keys[3:7] {
+		caches.Add(key, 1)
+	}
+	require.Equal(t, budget, caches.Total())
+
+	// the seen set is evicted from first, starting with the entry added first
+	require.Equal(t, 3, caches.dedup.Len())
+	require.Equal(t, 2, caches.seen.Len())
+	require.False(t, caches.seen.Has(keys[3], 1))
+	require.True(t, caches.seen.Has(keys[6], 1))
+
+	// once the seen set is exhausted the dedup cache is evicted from
+	for _, key := range keys[7:] {
+		caches.Push(key)
+	}
+	require.Equal(t, budget, caches.Total())
+	require.Zero(t, caches.seen.Len())
+	require.False(t, caches.dedup.Has(keys[0]))
+	require.True(t, caches.dedup.Has(keys[9]))
+}
+
+func TestTxCachesNoBudget(t *testing.T) {
+	keys := testTxKeys(10)
+	caches := NewTxCaches(NewLRUTxCache(10), NewSeenTxSet(), 0)
+	for _, key := range keys {
+		caches.Add(key, 1)
+		caches.Push(key)
+	}
+	require.Equal(t, 20, caches.Total())
+}
+
+func TestTxCachesAddSkipsDedupedKeys(t *testing.T) {
+	keys := testTxKeys(2)
+	caches := NewTxCaches(NewLRUTxCache(10), NewSeenTxSet(), 0)
+	caches.Push(keys[0])
+	caches.Add(keys[0], 1)
+	caches.Add(keys[1], 1)
+	require.False(t, caches.seen.Has(keys[0], 1))
+	require.Nil(t, caches.seen.Get(keys[0]))
+	require.True(t, caches.seen.Has(keys[1], 1))
+	// the lookups are not counted as hits or misses of the dedup cache
+	require.Equal(t, LRUTxCacheStats{Inserts: 1}, caches.dedup.Stats())
+}
+
+func TestLookupTx(t *testing.T) {
+	keys := testTxKeys(4)
+	dedup := NewLRUTxCache(10)
+	seen := NewSeenTxSet()
+
+	dedup.Push(keys[1])
+	seen.Add(keys[2], 1)
+	seen.Add(keys[2], 2)
+	dedup.Push(keys[3])
+	seen.Add(keys[3], 1)
+
+	testCases := []struct {
+		key    types.TxKey
+		status TxCacheStatus
+	}{
+		{keys[0], TxCacheStatus{}},
+		{keys[1], TxCacheStatus{InDedup: true}},
+		{keys[2], TxCacheStatus{SeenByPeers: 2}},
+		{keys[3], TxCacheStatus{InDedup: true, SeenByPeers: 1}},
+	}
+	for i, tc := range testCases {
+		require.Equal(t, tc.status, LookupTx(dedup, seen, tc.key), i)
+	}
+	// diagnostics do not skew the dedup cache's hit rate
+	require.Equal(t, LRUTxCacheStats{Inserts: 2}, dedup.Stats())
+}
+
+func TestWriteReport(t *testing.T) {
+	keys := testTxKeys(4)
+	dedup := NewLRUTxCache(10)
+	seen := NewSeenTxSet()
+	dedup.Push(keys[0])
+	dedup.Push(keys[1])
+	seen.Add(keys[2], 1)
+	seen.Add(keys[2], 2)
+	seen.Add(keys[3], 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteReport(&buf, dedup, seen))
+	report := buf.String()
+	require.Contains(t, report, "dedup cache")
+	require.Contains(t, report, "fill: 2/10 (20.0%)")
+	require.Contains(t, report, "seen tx set")
+	require.Contains(t, report, "entries: 2")
+	require.Contains(t, report, "peers tracked: 2")
+	require.Contains(t, report, "oldest entry age: ")
+
+	// the fill is relative to the capacity currently in effect
+	dedup.SetMemoryPressure(true)
+	buf.Reset()
+	require.NoError(t, WriteReport(&buf, dedup, seen))
+	require.Contains(t, buf.String(), "fill: 2/5 (40.0%)")
+}
+
+func TestTxCachesShouldSend(t *testing.T) {
+	keys := testTxKeys(4)
+	caches := NewTxCaches(NewLRUTxCache(10), NewSeenTxSet(), 0)
+	caches.Add(keys[1], 1)
+	require.True(t, caches.ShouldSend(keys[0], 1))
+	require.False(t, caches.ShouldSend(keys[1], 1))
+
+	sent := NewSentTxSet()
+	caches.SetSentTxSet(sent)
+	sent.Add(keys[2], 1)
+	caches.Add(keys[3], 1)
+	sent.Add(keys[3], 1)
+
+	for _, tc := range []struct {
+		name       string
+		key        types.TxKey
+		shouldSend bool
+	}{
+		{"neither seen nor sent", keys[0], true},
+		{"seen", keys[1], false},
+		{"sent", keys[2], false},
+		{"seen and sent", keys[3], false},
+	} {
+		require.Equal(t, tc.shouldSend, caches.ShouldSend(tc.key, 1), tc.name)
+	}
+	// other peers are unaffected
+	require.True(t, caches.ShouldSend(keys[3], 2))
+}
+
+func TestTxCachesReconcile(t *testing.T) {
+	keys := testTxKeys(4)
+	caches := NewTxCaches(NewLRUTxCache(10), NewSeenTxSet(), 0)
+	for _, key := range keys {
+		caches.Add(key, 1)
+	}
+
+	// keys 0 and 1 have arrived in the pool but their seen entries drifted
+	present := map[types.TxKey]bool{keys[0]: true, keys[1]: true, keys[2]: false}
+	require.Equal(t, 2, caches.Reconcile(present))
+	require.False(t, caches.seen.Has(keys[0], 1))
+	require.False(t, caches.seen.Has(keys[1], 1))
+	require.True(t, caches.seen.Has(keys[2], 1))
+	require.True(t, caches.seen.Has(keys[3], 1))
+
+	require.Zero(t, caches.Reconcile(present))
+}
+
+func TestTxCachesResetAll(t *testing.T) {
+	keys := testTxKeys(5)
+	caches := NewTxCaches(NewLRUTxCache(10), NewSeenTxSet(), 0)
+	for _, key := range keys {
+		caches.Add(key, 1)
+		caches.Push(key)
+	}
+	require.Equal(t, 10, caches.Total())
+
+	caches.ResetAll()
+	require.Zero(t, caches.Total())
+	require.Zero(t, caches.dedup.Len())
+	require.Zero(t, caches.seen.Len())
+	require.False(t, caches.dedup.Has(keys[0]))
+}
+
+func TestCacheOpLog(t *testing.T) {
+	keys := testTxKeys(3)
+
+	t.Run("dedup", func(t *testing.T) {
+		cache := NewLRUTxCache(2)
+		cache.Push(keys[0])
+		require.Nil(t, cache.RecentOps())
+
+		cache.EnableOpLog(4)
+		cache.Push(keys[1])
+		cache.Push(keys[1])
+		cache.Push(keys[2])
+		cache.Has(keys[0])
+		cache.Remove(keys[2])
+
+		ops := cache.RecentOps()
+		require.Len(t, ops, 4)
+		type op struct {
+			name   string
+			key    types.TxKey
+			result bool
+		}
+		got := make([]op, len(ops))
+		for i, o := range ops {
+			got[i] = op{o.Op, o.Key, o.Result}
+			require.False(t, o.Time.IsZero())
+		}
+		// the first two pushes were overwritten once the log filled up
+		require.Equal(t, []op{
+			{"push", keys[2], true},
+			{"evict", keys[0], true},
+			{"has", keys[0], false},
+			{"remove", keys[2], true},
+		}, got)
+	})
+
+	t.Run("seen", func(t *testing.T) {
+		seenSet := NewSeenTxSet()
+		seenSet.EnableOpLog(10)
+		seenSet.Add(keys[0], 1)
+		seenSet.Add(keys[0], 1)
+		seenSet.Pop(keys[0])
+		seenSet.Remove(keys[1], 1)
+		seenSet.RemoveKey(keys[0])
+
+		ops := seenSet.RecentOps()
+		require.Len(t, ops, 4)
+		for i, want := range []CacheOp{
+			{Op: "add", Key: keys[0], Result: true},
+			{Op: "add", Key: keys[0], Result: false},
+			{Op: "pop", Key: keys[0], Result: true},
+			{Op: "removeKey", Key: keys[0], Result: true},
+		} {
+			require.Equal(t, want.Op, ops[i].Op)
+			require.Equal(t, want.Key, ops[i].Key)
+			require.Equal(t, want.Result, ops[i].Result)
+		}
+
+		seenSet.EnableOpLog(0)
+		seenSet.Add(keys[2], 1)
+		require.Nil(t, seenSet.RecentOps())
+	})
+}
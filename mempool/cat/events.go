@@ -0,0 +1,66 @@
+package cat
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+// EvictionReason describes why a transaction was evicted.
+type EvictionReason string
+
+const (
+	// EvictionReasonFull means the transaction was removed from the full
+	// mempool to make room for one of higher priority.
+	EvictionReasonFull EvictionReason = "full"
+	// EvictionReasonExpired means the transaction outlived the mempool's TTL.
+	EvictionReasonExpired EvictionReason = "expired"
+	// EvictionReasonDedup means the key was evicted from the cache of rejected
+	// transactions, so the transaction is no longer instantly rejected.
+	EvictionReasonDedup EvictionReason = "dedup"
+)
+
+// EventEvictedTx is published to the EvictionSink for every evicted
+// transaction.
+type EventEvictedTx struct {
+	TxKey  types.TxKey
+	Reason EvictionReason
+}
+
+const (
+	// EventTypeEvictedTx is the value of types.EventTypeKey for eviction
+	// events, e.g. tm.event='EvictedTx' AND evicted_tx.reason='full'.
+	EventTypeEvictedTx = "EvictedTx"
+	// EvictedTxKeyKey is the event key holding the hex encoded tx key.
+	EvictedTxKeyKey = "evicted_tx.key"
+	// EvictedTxReasonKey is the event key holding the EvictionReason.
+	EvictedTxReasonKey = "evicted_tx.reason"
+)
+
+const (
+	// evictionQueueSize is the number of eviction events buffered for the
+	// sink before further ones are dropped.
+	evictionQueueSize = 1024
+	// evictionPublishTimeout bounds how long publishing a single eviction may
+	// block on the sink.
+	evictionPublishTimeout = 100 * time.Millisecond
+)
+
+// EvictionSink receives eviction events, e.g. so that indexers and subscribers
+// can update their views of pending transactions. It is satisfied by
+// *pubsub.Server. See WithEvictionSink.
+type EvictionSink interface {
+	PublishWithEvents(ctx context.Context, msg interface{}, events map[string][]string) error
+}
+
+// events returns the events the eviction is published with, which queries can
+// match on.
+func (e EventEvictedTx) events() map[string][]string {
+	return map[string][]string{
+		types.EventTypeKey: {EventTypeEvictedTx},
+		EvictedTxKeyKey:    {fmt.Sprintf("%X", e.TxKey[:])},
+		EvictedTxReasonKey: {string(e.Reason)},
+	}
+}
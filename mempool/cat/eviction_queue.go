@@ -0,0 +1,98 @@
+package cat
+
+import (
+	"sync/atomic"
+
+	tmsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/types"
+)
+
+// EvictionQueue runs eviction callbacks on a dedicated goroutine so that slow
+// callbacks, e.g. ones writing to another cache or emitting metrics, do not add
+// latency to Push. Hand its Enqueue method to LRUTxCache.SetOnEvict:
+//
+//	queue := NewEvictionQueue(1024, onEvict)
+//	defer queue.Stop()
+//	cache.SetOnEvict(queue.Enqueue)
+//
+// The queue is bounded; keys evicted while it is full are dropped and counted
+// rather than blocking the cache.
+type EvictionQueue struct {
+	handle  func(event EventEvictedTx)
+	queue   chan EventEvictedTx
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	// mtx guards stopped so that Enqueue never sends on the closed queue
+	mtx     tmsync.RWMutex
+	stopped bool
+}
+
+// NewEvictionQueue starts a worker calling onEvict with the keys enqueued, of
+// which it buffers up to size. A negative size is treated as 0, in which case
+// keys are only handed over while the worker is idle.
+func NewEvictionQueue(size int, onEvict func(txKey types.TxKey)) *EvictionQueue {
+	return newEvictionQueue(size, func(event EventEvictedTx) { onEvict(event.TxKey) })
+}
+
+// newEvictionQueue is like NewEvictionQueue but hands the worker whole events,
+// so that a single queue can carry evictions of every reason.
+func newEvictionQueue(size int, handle func(event EventEvictedTx)) *EvictionQueue {
+	if size < 0 {
+		size = 0
+	}
+	q := &EvictionQueue{
+		handle: handle,
+		queue:  make(chan EventEvictedTx, size),
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *EvictionQueue) run() {
+	defer close(q.done)
+	for event := range q.queue {
+		q.handle(event)
+	}
+}
+
+// Enqueue hands the key to the worker without blocking. The key is dropped if
+// the queue is full or stopped.
+func (q *EvictionQueue) Enqueue(txKey types.TxKey) {
+	q.enqueue(EventEvictedTx{TxKey: txKey})
+}
+
+// enqueue hands the event to the worker without blocking, dropping it if the
+// queue is full or stopped.
+func (q *EvictionQueue) enqueue(event EventEvictedTx) {
+	q.mtx.RLock()
+	defer q.mtx.RUnlock()
+	if q.stopped {
+		q.dropped.Add(1)
+		return
+	}
+	select {
+	case q.queue <- event:
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of keys dropped because the queue was full or
+// stopped.
+func (q *EvictionQueue) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Stop stops accepting keys and waits for the worker to process those already
+// queued. It is safe to call more than once.
+func (q *EvictionQueue) Stop() {
+	q.mtx.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.queue)
+	}
+	q.mtx.Unlock()
+	<-q.done
+}
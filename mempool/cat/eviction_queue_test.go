@@ -0,0 +1,102 @@
+package cat
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestLRUTxCacheOnEvict(t *testing.T) {
+	keys := testTxKeys(4)
+	cache := NewLRUTxCache(2)
+	var evicted []types.TxKey
+	cache.SetOnEvict(func(txKey types.TxKey) { evicted = append(evicted, txKey) })
+	for _, key := range keys {
+		cache.Push(key)
+	}
+	// removals are not evictions
+	cache.Remove(keys[3])
+	require.Equal(t, keys[:2], evicted)
+
+	cache.SetOnEvict(nil)
+	cache.Push(keys[0])
+	cache.Push(keys[1])
+	require.Len(t, evicted, 2)
+}
+
+func TestEvictionQueue(t *testing.T) {
+	keys := testTxKeys(10)
+	var (
+		mtx     sync.Mutex
+		evicted []types.TxKey
+	)
+	queue := NewEvictionQueue(4, func(txKey types.TxKey) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		evicted = append(evicted, txKey)
+	})
+	defer queue.Stop()
+
+	cache := NewLRUTxCache(2)
+	cache.SetOnEvict(queue.Enqueue)
+	for _, key := range keys[:5] {
+		cache.Push(key)
+	}
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(evicted) == 3
+	}, time.Second, time.Millisecond)
+	require.Equal(t, keys[:3], evicted)
+	require.Zero(t, queue.Dropped())
+}
+
+func TestEvictionQueueNegativeSize(t *testing.T) {
+	txKey := types.Tx("tx").Key()
+	done := make(chan types.TxKey, 1)
+	queue := NewEvictionQueue(-1, func(txKey types.TxKey) { done <- txKey })
+	defer queue.Stop()
+
+	// without a buffer a key is only taken while the worker is waiting for one
+	require.Eventually(t, func() bool {
+		queue.Enqueue(txKey)
+		return len(done) == 1
+	}, time.Second, time.Millisecond)
+	require.Equal(t, txKey, <-done)
+}
+
+func TestEvictionQueueBurst(t *testing.T) {
+	const size = 2
+	keys := testTxKeys(11)
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	queue := NewEvictionQueue(size, func(txKey types.TxKey) {
+		if txKey == keys[0] {
+			close(started)
+			<-release
+		}
+		wg.Done()
+	})
+
+	// block the worker on the first key, then burst past the queue's size
+	wg.Add(1 + size)
+	queue.Enqueue(keys[0])
+	<-started
+	for _, key := range keys[1:] {
+		queue.Enqueue(key)
+	}
+	require.EqualValues(t, len(keys)-1-size, queue.Dropped())
+
+	close(release)
+	wg.Wait()
+	queue.Stop()
+	queue.Enqueue(keys[0])
+	require.EqualValues(t, len(keys)-size, queue.Dropped())
+}
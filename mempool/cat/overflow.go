@@ -0,0 +1,217 @@
+package cat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tmsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/types"
+)
+
+const (
+	// overflowSlotSize is the size of a slot in an overflow table: a state byte
+	// followed by the key
+	overflowSlotSize = 1 + types.TxKeySize
+
+	overflowSlotEmpty   byte = 0
+	overflowSlotPresent byte = 1
+	overflowSlotRemoved byte = 2
+)
+
+// DiskOverflow is a bounded, disk backed set of tx keys that extends the
+// dedup window of an LRUTxCache beyond what it holds in memory. See
+// LRUTxCache.SetOverflow.
+//
+// Keys are stored in two generations, each an open addressing hash table in
+// its own file with room for twice the limit so that probes stay short. Keys
+// are added to the current generation. Compact drops the previous generation
+// and starts a new current one, so the overflow never holds more than twice
+// the limit. Compaction happens automatically once the current generation
+// holds limit keys.
+type DiskOverflow struct {
+	mtx tmsync.Mutex
+	// limit is the number of keys per generation
+	limit int
+	// current receives new keys; previous is only read until it is dropped
+	current  *overflowTable
+	previous *overflowTable
+}
+
+// overflowTable is one generation of a DiskOverflow
+type overflowTable struct {
+	file  *os.File
+	slots uint64
+	// used counts the non empty slots
+	used int
+}
+
+// NewDiskOverflow creates the files of an overflow holding up to limit keys
+// per generation in dir, truncating any previous contents.
+func NewDiskOverflow(dir string, limit int) (*DiskOverflow, error) {
+	if limit < 1 {
+		return nil, ErrInvalidSize
+	}
+	o := &DiskOverflow{limit: limit}
+	tables := make([]*overflowTable, 2)
+	for i := range tables {
+		file, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("overflow-%d.db", i)), os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			o.closeTables(tables[:i])
+			return nil, err
+		}
+		tables[i] = &overflowTable{file: file, slots: uint64(2 * limit)}
+		if err := tables[i].clear(); err != nil {
+			o.closeTables(tables[:i+1])
+			return nil, err
+		}
+	}
+	o.current, o.previous = tables[0], tables[1]
+	return o, nil
+}
+
+// Push adds the key to the current generation of the overflow, compacting first
+// if the generation is full.
+func (o *DiskOverflow) Push(txKey types.TxKey) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	state, _, err := o.current.find(txKey)
+	if err != nil || state == overflowSlotPresent {
+		return err
+	}
+	if o.current.used >= o.limit {
+		if err := o.compact(); err != nil {
+			return err
+		}
+	}
+	return o.current.put(txKey)
+}
+
+// Has reports whether the key is in either generation of the overflow.
+func (o *DiskOverflow) Has(txKey types.TxKey) (bool, error) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	for _, table := range []*overflowTable{o.current, o.previous} {
+		state, _, err := table.find(txKey)
+		if err != nil {
+			return false, err
+		}
+		if state == overflowSlotPresent {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Remove deletes the key from the overflow.
+func (o *DiskOverflow) Remove(txKey types.TxKey) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	for _, table := range []*overflowTable{o.current, o.previous} {
+		if err := table.remove(txKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact drops the keys of the previous generation and starts a new current
+// generation.
+func (o *DiskOverflow) Compact() error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.compact()
+}
+
+// compact drops the previous generation and starts a new current one.
+// This assumes that the overflow's mutex is already locked.
+func (o *DiskOverflow) compact() error {
+	if err := o.previous.clear(); err != nil {
+		return err
+	}
+	o.current, o.previous = o.previous, o.current
+	return nil
+}
+
+// Reset deletes all keys.
+func (o *DiskOverflow) Reset() error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return errors.Join(o.current.clear(), o.previous.clear())
+}
+
+// Close closes the overflow's files.
+func (o *DiskOverflow) Close() error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.closeTables([]*overflowTable{o.current, o.previous})
+}
+
+func (o *DiskOverflow) closeTables(tables []*overflowTable) error {
+	var err error
+	for _, table := range tables {
+		err = errors.Join(err, table.file.Close())
+	}
+	return err
+}
+
+// clear empties the table by zeroing its file.
+func (t *overflowTable) clear() error {
+	if err := t.file.Truncate(0); err != nil {
+		return err
+	}
+	t.used = 0
+	return t.file.Truncate(int64(t.slots * overflowSlotSize))
+}
+
+// find probes the table for the key. It returns the state of the slot holding
+// the key and its index, or overflowSlotEmpty and the index of the first empty
+// slot if the key is not in the table.
+func (t *overflowTable) find(txKey types.TxKey) (byte, uint64, error) {
+	slot := make([]byte, overflowSlotSize)
+	start := binary.BigEndian.Uint64(txKey[8:16]) % t.slots
+	for i := uint64(0); i < t.slots; i++ {
+		index := (start + i) % t.slots
+		if _, err := t.file.ReadAt(slot, int64(index*overflowSlotSize)); err != nil {
+			return overflowSlotEmpty, 0, err
+		}
+		if slot[0] == overflowSlotEmpty || bytes.Equal(slot[1:], txKey[:]) {
+			return slot[0], index, nil
+		}
+	}
+	// the table is never more than half full so there is always an empty slot
+	return overflowSlotEmpty, 0, errors.New("overflow table is full")
+}
+
+// put adds the key to the table, reusing its slot if it was removed.
+func (t *overflowTable) put(txKey types.TxKey) error {
+	state, index, err := t.find(txKey)
+	if err != nil || state == overflowSlotPresent {
+		return err
+	}
+	slot := append([]byte{overflowSlotPresent}, txKey[:]...)
+	if _, err := t.file.WriteAt(slot, int64(index*overflowSlotSize)); err != nil {
+		return err
+	}
+	if state == overflowSlotEmpty {
+		t.used++
+	}
+	return nil
+}
+
+// remove marks the key's slot as removed. Its slot is kept so that probes for
+// other keys continue past it.
+func (t *overflowTable) remove(txKey types.TxKey) error {
+	state, index, err := t.find(txKey)
+	if err != nil || state != overflowSlotPresent {
+		return err
+	}
+	_, err = t.file.WriteAt([]byte{overflowSlotRemoved}, int64(index*overflowSlotSize))
+	return err
+}
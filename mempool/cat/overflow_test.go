@@ -0,0 +1,133 @@
+package cat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestDiskOverflow(t *testing.T) {
+	_, err := NewDiskOverflow(t.TempDir(), 0)
+	require.ErrorIs(t, err, ErrInvalidSize)
+
+	keys := testTxKeys(10)
+	overflow, err := NewDiskOverflow(t.TempDir(), 4)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, overflow.Close()) })
+
+	for _, key := range keys[:4] {
+		require.NoError(t, overflow.Push(key))
+	}
+	// pushing a key twice does not use up another slot
+	require.NoError(t, overflow.Push(keys[0]))
+	for _, key := range keys[:4] {
+		has, err := overflow.Has(key)
+		require.NoError(t, err)
+		require.True(t, has)
+	}
+
+	require.NoError(t, overflow.Remove(keys[1]))
+	has, err := overflow.Has(keys[1])
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// the current generation is full, so the next push compacts and the
+	// first generation is kept until the following compaction
+	require.NoError(t, overflow.Push(keys[4]))
+	has, err = overflow.Has(keys[0])
+	require.NoError(t, err)
+	require.True(t, has)
+	require.NoError(t, overflow.Compact())
+	for _, key := range keys[:5] {
+		has, err := overflow.Has(key)
+		require.NoError(t, err)
+		require.Equal(t, key == keys[4], has)
+	}
+
+	require.NoError(t, overflow.Reset())
+	has, err = overflow.Has(keys[4])
+	require.NoError(t, err)
+	require.False(t, has)
+}
+
+func TestLRUTxCacheOverflow(t *testing.T) {
+	keys := testTxKeys(10)
+	overflow, err := NewDiskOverflow(t.TempDir(), 5)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, overflow.Close()) })
+
+	cache := NewLRUTxCache(2)
+	cache.SetOverflow(overflow)
+	for _, key := range keys[:4] {
+		cache.Push(key)
+	}
+	// the evicted keys are still reported present via the overflow
+	require.Equal(t, 2, cache.Len())
+	for _, key := range keys[:4] {
+		require.True(t, cache.Has(key))
+	}
+
+	cache.Remove(keys[0])
+	require.False(t, cache.Has(keys[0]))
+
+	// once compaction has dropped their generation they are absent
+	require.NoError(t, overflow.Compact())
+	require.NoError(t, overflow.Compact())
+	require.False(t, cache.Has(keys[1]))
+	require.True(t, cache.Has(keys[3]))
+}
+
+func TestLRUTxCacheOverflowPush(t *testing.T) {
+	keys := testTxKeys(4)
+	overflow, err := NewDiskOverflow(t.TempDir(), 5)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, overflow.Close()) })
+
+	cache := NewLRUTxCache(2)
+	cache.SetOverflow(overflow)
+	for _, key := range keys[:3] {
+		require.True(t, cache.Push(key))
+	}
+	// keys[0] has been spilled to the overflow
+	require.Equal(t, []types.TxKey{keys[1], keys[2]}, cache.OrderedKeys())
+	require.True(t, cache.Has(keys[0]))
+	require.Equal(t, []types.TxKey{keys[3]}, cache.Missing(keys))
+
+	require.False(t, cache.CompareAndPush(keys[0]))
+	require.NoError(t, cache.TryPush(keys[0]))
+	require.Equal(t, []types.TxKey{keys[1], keys[2]}, cache.OrderedKeys())
+
+	// pushing it again brings it back into memory, spilling the oldest key
+	require.False(t, cache.Push(keys[0]))
+	require.Equal(t, []types.TxKey{keys[2], keys[0]}, cache.OrderedKeys())
+	has, err := overflow.Has(keys[0])
+	require.NoError(t, err)
+	require.False(t, has)
+	require.True(t, cache.Has(keys[1]))
+
+	require.False(t, cache.PushLocal(keys[1]))
+	require.False(t, cache.PushWithSender(keys[2], "sender"))
+	require.Equal(t, 2, cache.Len())
+	require.Empty(t, cache.Missing(keys[:3]))
+}
+
+func TestDiffOverflow(t *testing.T) {
+	keys := testTxKeys(3)
+	overflow, err := NewDiskOverflow(t.TempDir(), 5)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, overflow.Close()) })
+
+	primary, replica := NewLRUTxCache(3), NewLRUTxCache(2)
+	replica.SetOverflow(overflow)
+	for _, key := range keys {
+		primary.Push(key)
+		replica.Push(key)
+	}
+	// keys[0] was spilled by the replica, which still reports it as cached
+	require.True(t, replica.Has(keys[0]))
+	onlyPrimary, onlyReplica := Diff(primary, replica)
+	require.Empty(t, onlyPrimary)
+	require.Empty(t, onlyReplica)
+}
@@ -1,6 +1,7 @@
 package cat
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
@@ -63,9 +64,14 @@ type TxPool struct {
 	lastPurgeTime        time.Time // the last time we attempted to purge transactions via the TTL
 
 	// Thread-safe cache of rejected transactions for quick look-up
-	rejectedTxCache *LRUTxCache
+	rejectedTxCache TxCacheBackend
 	// Thread-safe list of transactions peers have seen that we have not yet seen
 	seenByPeersSet *SeenTxSet
+	// evictionSink optionally receives an event for every evicted transaction
+	evictionSink EvictionSink
+	// evictions hands the events for evictionSink to a worker so that no
+	// caller waits on the sink. It is only set together with evictionSink.
+	evictions *EvictionQueue
 
 	// Store of wrapped transactions
 	store *store
@@ -105,6 +111,15 @@ func NewTxPool(
 		opt(txmp)
 	}
 
+	if txmp.evictionSink != nil {
+		txmp.evictions = newEvictionQueue(evictionQueueSize, txmp.publish)
+		if cache, ok := txmp.rejectedTxCache.(*LRUTxCache); ok {
+			cache.SetOnEvict(func(txKey types.TxKey) {
+				txmp.publishEviction(txKey, EvictionReasonDedup)
+			})
+		}
+	}
+
 	return txmp
 }
 
@@ -122,6 +137,29 @@ func WithPostCheck(f mempool.PostCheckFunc) TxPoolOption {
 	return func(txmp *TxPool) { txmp.postCheckFn = f }
 }
 
+// WithTxCacheBackend replaces the in-memory cache of rejected transactions with
+// the given backend, e.g. an adapter for an external store.
+func WithTxCacheBackend(backend TxCacheBackend) TxPoolOption {
+	return func(txmp *TxPool) { txmp.rejectedTxCache = backend }
+}
+
+// WithSeenPruneJitter randomizes the TTL used to prune the transactions seen
+// by peers within ±fraction, de-synchronizing pruning across nodes.
+func WithSeenPruneJitter(fraction float64) TxPoolOption {
+	return func(txmp *TxPool) { txmp.seenByPeersSet.SetPruneJitter(fraction) }
+}
+
+// WithEvictionSink publishes an EventEvictedTx to the sink whenever a
+// transaction is evicted from the mempool or, if the cache of rejected
+// transactions is an LRUTxCache, from that cache. For the latter the cache's
+// OnEvict callback is replaced. Events are published from a bounded
+// EvictionQueue so that a slow sink never holds up CheckTx, Update or the
+// cache; events are dropped while the queue is full and each publish gives up
+// after evictionPublishTimeout. Call Close to stop publishing.
+func WithEvictionSink(sink EvictionSink) TxPoolOption {
+	return func(txmp *TxPool) { txmp.evictionSink = sink }
+}
+
 // WithMetrics sets the mempool's metrics collector.
 func WithMetrics(metrics *mempool.Metrics) TxPoolOption {
 	return func(txmp *TxPool) { txmp.metrics = metrics }
@@ -197,8 +235,9 @@ func (txmp *TxPool) CheckToPurgeExpiredTxs() {
 		expirationAge := time.Now().Add(-txmp.config.TTLDuration)
 		// a height of 0 means no transactions will be removed because of height
 		// (in other words, no transaction has a height less than 0)
-		numExpired := txmp.store.purgeExpiredTxs(0, expirationAge)
-		txmp.metrics.EvictedTxs.Add(float64(numExpired))
+		expired := txmp.store.purgeExpiredTxs(0, expirationAge)
+		txmp.metrics.EvictedTxs.Add(float64(len(expired)))
+		txmp.publishExpired(expired)
 		txmp.lastPurgeTime = time.Now()
 	}
 }
@@ -593,6 +632,49 @@ func (txmp *TxPool) evictTx(wtx *wrappedTx) {
 		"old_tx", fmt.Sprintf("%X", wtx.key),
 		"old_priority", wtx.priority,
 	)
+	// the placeholder of a reserved key is not a transaction to report
+	if wtx.height != -1 {
+		txmp.publishEviction(wtx.key, EvictionReasonFull)
+	}
+}
+
+// publishEviction queues an EventEvictedTx for the eviction sink, if any,
+// without blocking.
+func (txmp *TxPool) publishEviction(txKey types.TxKey, reason EvictionReason) {
+	if txmp.evictions == nil {
+		return
+	}
+	txmp.evictions.enqueue(EventEvictedTx{TxKey: txKey, Reason: reason})
+}
+
+// publishExpired queues an EventEvictedTx for each of the expired transactions,
+// skipping the placeholders of reserved keys.
+func (txmp *TxPool) publishExpired(expired []*wrappedTx) {
+	for _, wtx := range expired {
+		if wtx.height != -1 {
+			txmp.publishEviction(wtx.key, EvictionReasonExpired)
+		}
+	}
+}
+
+// publish hands the event to the eviction sink. It runs on the worker of the
+// eviction queue.
+func (txmp *TxPool) publish(event EventEvictedTx) {
+	ctx, cancel := context.WithTimeout(context.Background(), evictionPublishTimeout)
+	defer cancel()
+	if err := txmp.evictionSink.PublishWithEvents(ctx, event, event.events()); err != nil {
+		txmp.logger.Error("failed to publish tx eviction",
+			"txKey", event.TxKey, "reason", event.Reason, "err", err)
+	}
+}
+
+// Close stops publishing evictions to the sink set with WithEvictionSink,
+// waiting for the events already queued to be published. It is a no-op without
+// a sink and safe to call more than once.
+func (txmp *TxPool) Close() {
+	if txmp.evictions != nil {
+		txmp.evictions.Stop()
+	}
 }
 
 // handleRecheckResult handles the responses from ABCI CheckTx calls issued
@@ -715,8 +797,9 @@ func (txmp *TxPool) purgeExpiredTxs(blockHeight int64) {
 		expirationAge = time.Time{}
 	}
 
-	numExpired := txmp.store.purgeExpiredTxs(expirationHeight, expirationAge)
-	txmp.metrics.EvictedTxs.Add(float64(numExpired))
+	expired := txmp.store.purgeExpiredTxs(expirationHeight, expirationAge)
+	txmp.metrics.EvictedTxs.Add(float64(len(expired)))
+	txmp.publishExpired(expired)
 
 	// purge old evicted and seen transactions
 	if txmp.config.TTLDuration == 0 {
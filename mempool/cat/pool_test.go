@@ -21,6 +21,8 @@ import (
 	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/config"
 	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cometbft/cometbft/libs/pubsub"
+	"github.com/cometbft/cometbft/libs/pubsub/query"
 	"github.com/cometbft/cometbft/mempool"
 	"github.com/cometbft/cometbft/pkg/consts"
 	tmproto "github.com/cometbft/cometbft/proto/tendermint/types"
@@ -101,7 +103,9 @@ func setup(t testing.TB, cacheSize int, options ...TxPoolOption) *TxPool {
 		require.NoError(t, appConnMem.Stop())
 	})
 
-	return NewTxPool(log.TestingLogger().With("test", t.Name()), cfg, appConnMem, 1, options...)
+	txmp := NewTxPool(log.TestingLogger().With("test", t.Name()), cfg, appConnMem, 1, options...)
+	t.Cleanup(txmp.Close)
+	return txmp
 }
 
 // mustCheckTx invokes txmp.CheckTx for the given transaction and waits until
@@ -323,6 +327,200 @@ func TestTxPool_Flush(t *testing.T) {
 	require.Equal(t, int64(0), txmp.SizeBytes())
 }
 
+// mapTxCacheBackend is an unbounded TxCacheBackend standing in for an
+// external store
+type mapTxCacheBackend struct {
+	mtx  sync.Mutex
+	keys map[types.TxKey]struct{}
+}
+
+func (b *mapTxCacheBackend) Has(txKey types.TxKey) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	_, ok := b.keys[txKey]
+	return ok
+}
+
+func (b *mapTxCacheBackend) Push(txKey types.TxKey) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if _, ok := b.keys[txKey]; ok {
+		return false
+	}
+	b.keys[txKey] = struct{}{}
+	return true
+}
+
+func (b *mapTxCacheBackend) Remove(txKey types.TxKey) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	delete(b.keys, txKey)
+}
+
+func (b *mapTxCacheBackend) Reset() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.keys = make(map[types.TxKey]struct{})
+}
+
+func TestTxPool_TxCacheBackend(t *testing.T) {
+	backend := &mapTxCacheBackend{keys: make(map[types.TxKey]struct{})}
+	txmp := setup(t, 100, WithTxCacheBackend(backend))
+	txs := checkTxs(t, txmp, 2, 0)
+	key := txs[0].tx.Key()
+
+	// removed txs are remembered by the backend and not added again
+	require.NoError(t, txmp.RemoveTxByKey(key))
+	require.True(t, backend.Has(key))
+	require.True(t, txmp.IsRejectedTx(key))
+	_, err := txmp.TryAddNewTx(txs[0].tx, key, mempool.TxInfo{})
+	require.ErrorIs(t, err, ErrTxAlreadyRejected)
+	require.Equal(t, 1, txmp.Size())
+
+	// flushing resets the backend
+	txmp.Flush()
+	require.False(t, backend.Has(key))
+	_, err = txmp.TryAddNewTx(txs[0].tx, key, mempool.TxInfo{})
+	require.NoError(t, err)
+	require.Equal(t, 1, txmp.Size())
+}
+
+type mockEvictionSink struct {
+	mtx    sync.Mutex
+	events []EventEvictedTx
+}
+
+func (s *mockEvictionSink) PublishWithEvents(_ context.Context, msg interface{}, _ map[string][]string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.events = append(s.events, msg.(EventEvictedTx))
+	return nil
+}
+
+func (s *mockEvictionSink) reasons() map[types.TxKey]EvictionReason {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	reasons := make(map[types.TxKey]EvictionReason, len(s.events))
+	for _, event := range s.events {
+		reasons[event.TxKey] = event.Reason
+	}
+	return reasons
+}
+
+func (s *mockEvictionSink) len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.events)
+}
+
+// requireReasons waits for the events, which are published asynchronously, to
+// match the expected reasons.
+func (s *mockEvictionSink) requireReasons(t *testing.T, expected map[types.TxKey]EvictionReason) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, s.reasons())
+	}, time.Second, time.Millisecond)
+}
+
+func TestTxPool_EvictionSink(t *testing.T) {
+	sink := &mockEvictionSink{}
+	txmp := setup(t, 1, WithEvictionSink(sink))
+	// room for two of the txs below
+	txmp.config.MaxTxsBytes = 22
+
+	// a higher priority tx evicts the lowest priority one from the full pool
+	mustCheckTx(t, txmp, "key1=0000=1")
+	mustCheckTx(t, txmp, "key2=0001=2")
+	mustCheckTx(t, txmp, "key3=0002=3")
+	expected := map[types.TxKey]EvictionReason{
+		types.Tx("key1=0000=1").Key(): EvictionReasonFull,
+	}
+	sink.requireReasons(t, expected)
+
+	// removed txs go to the rejected tx cache, which holds a single key
+	require.NoError(t, txmp.RemoveTxByKey(types.Tx("key2=0001=2").Key()))
+	require.NoError(t, txmp.RemoveTxByKey(types.Tx("key3=0002=3").Key()))
+	expected[types.Tx("key2=0001=2").Key()] = EvictionReasonDedup
+	sink.requireReasons(t, expected)
+
+	txmp.config.TTLDuration = time.Millisecond
+	mustCheckTx(t, txmp, "key4=0003=4")
+	time.Sleep(2 * time.Millisecond)
+	txmp.Lock()
+	require.NoError(t, txmp.Update(txmp.height+1, nil, nil, nil, nil))
+	txmp.Unlock()
+	expected[types.Tx("key4=0003=4").Key()] = EvictionReasonExpired
+	sink.requireReasons(t, expected)
+
+	// closing waits for the queued events, after which no more are published
+	txmp.Close()
+	require.Equal(t, 3, sink.len())
+	txmp.publishEviction(types.Tx("key5=0004=5").Key(), EvictionReasonFull)
+	require.Equal(t, 3, sink.len())
+}
+
+// blockingEvictionSink blocks every publish until release is closed.
+type blockingEvictionSink struct {
+	release chan struct{}
+}
+
+func (s *blockingEvictionSink) PublishWithEvents(context.Context, interface{}, map[string][]string) error {
+	<-s.release
+	return nil
+}
+
+func TestTxPool_EvictionSinkDoesNotBlock(t *testing.T) {
+	sink := &blockingEvictionSink{release: make(chan struct{})}
+	txmp := setup(t, 1, WithEvictionSink(sink))
+	txmp.config.MaxTxsBytes = 15
+	txmp.config.TTLDuration = time.Millisecond
+
+	// neither CheckTx nor Update wait for the sink to take the events
+	for i := 0; i < 2*evictionQueueSize; i++ {
+		mustCheckTx(t, txmp, fmt.Sprintf("k%04d=%04d=%04d", i, i, i+1))
+	}
+	time.Sleep(2 * time.Millisecond)
+	txmp.Lock()
+	require.NoError(t, txmp.Update(txmp.height+1, nil, nil, nil, nil))
+	txmp.Unlock()
+	require.Zero(t, txmp.Size())
+	require.NotZero(t, txmp.evictions.Dropped())
+
+	close(sink.release)
+	txmp.Close()
+}
+
+func TestTxPool_EvictionSinkQuery(t *testing.T) {
+	server := pubsub.NewServer()
+	require.NoError(t, server.Start())
+	t.Cleanup(func() {
+		if err := server.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	evicted := types.Tx("key1=0000=1").Key()
+	sub, err := server.Subscribe(context.Background(), "test", query.MustParse(fmt.Sprintf(
+		"%s='%s' AND %s='%s' AND %s='%X'",
+		types.EventTypeKey, EventTypeEvictedTx,
+		EvictedTxReasonKey, EvictionReasonFull,
+		EvictedTxKeyKey, evicted[:],
+	)))
+	require.NoError(t, err)
+
+	txmp := setup(t, 1, WithEvictionSink(server))
+	txmp.config.MaxTxsBytes = 11
+	mustCheckTx(t, txmp, "key1=0000=1")
+	mustCheckTx(t, txmp, "key2=0001=2")
+
+	select {
+	case msg := <-sub.Out():
+		require.Equal(t, EventEvictedTx{TxKey: evicted, Reason: EvictionReasonFull}, msg.Data())
+	case <-time.After(time.Second):
+		t.Fatal("eviction was not published to the matching subscription")
+	}
+}
+
 func TestTxPool_ReapMaxBytesMaxGas(t *testing.T) {
 	txmp := setup(t, 0)
 	tTxs := checkTxs(t, txmp, 100, 0) // all txs request 1 gas unit
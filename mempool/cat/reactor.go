@@ -136,6 +136,8 @@ func (memR *Reactor) OnStart() error {
 func (memR *Reactor) OnStop() {
 	// stop all the timers tracking outbound requests
 	memR.requests.Close()
+	// stop publishing evictions
+	memR.mempool.Close()
 }
 
 // GetChannels implements Reactor by returning the list of channels for this
@@ -0,0 +1,124 @@
+package cat
+
+import (
+	"container/list"
+
+	tmsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/types"
+)
+
+// SizedTxCache is a thread-safe LRU cache of transaction keys that is bounded
+// by the total size in bytes of the transactions it tracks rather than by the
+// number of entries. Only the key and the size of each transaction are stored.
+type SizedTxCache struct {
+	byteBudget int
+
+	mtx tmsync.Mutex
+	// bytes is the total size of all tracked transactions
+	bytes int
+	// cacheMap is used as a quick look up table
+	cacheMap map[types.TxKey]*list.Element
+	// list is a doubly linked list of sizedTxKey ordered from oldest to newest
+	list *list.List
+}
+
+type sizedTxKey struct {
+	key  types.TxKey
+	size int
+}
+
+// NewSizedTxCache returns a cache that tracks at most byteBudget bytes worth
+// of transactions. A budget of 0 disables the cache.
+func NewSizedTxCache(byteBudget int) *SizedTxCache {
+	return &SizedTxCache{
+		byteBudget: byteBudget,
+		cacheMap:   make(map[types.TxKey]*list.Element),
+		list:       list.New(),
+	}
+}
+
+func (c *SizedTxCache) Reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.bytes = 0
+	c.cacheMap = make(map[types.TxKey]*list.Element)
+	c.list.Init()
+}
+
+// PushSized adds the key of a transaction of the given size to the cache,
+// evicting the oldest entries until the total size is within the budget. It
+// returns false if the key is already cached or if the size is negative or
+// larger than the entire budget.
+func (c *SizedTxCache) PushSized(txKey types.TxKey, size int) bool {
+	if c.byteBudget == 0 {
+		return true
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if moved, ok := c.cacheMap[txKey]; ok {
+		c.list.MoveToBack(moved)
+		return false
+	}
+
+	if size < 0 || size > c.byteBudget {
+		return false
+	}
+
+	for c.bytes+size > c.byteBudget {
+		c.removeElement(c.list.Front())
+	}
+
+	c.cacheMap[txKey] = c.list.PushBack(sizedTxKey{key: txKey, size: size})
+	c.bytes += size
+	return true
+}
+
+func (c *SizedTxCache) Remove(txKey types.TxKey) {
+	if c.byteBudget == 0 {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if e, ok := c.cacheMap[txKey]; ok {
+		c.removeElement(e)
+	}
+}
+
+func (c *SizedTxCache) Has(txKey types.TxKey) bool {
+	if c.byteBudget == 0 {
+		return false
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	_, ok := c.cacheMap[txKey]
+	return ok
+}
+
+// Len returns the amount of cached keys.
+func (c *SizedTxCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.list.Len()
+}
+
+// Bytes returns the total size of the tracked transactions.
+func (c *SizedTxCache) Bytes() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.bytes
+}
+
+// removeElement deletes the element from the cache.
+// This assumes that the cache's mutex is already locked.
+func (c *SizedTxCache) removeElement(e *list.Element) {
+	entry := c.list.Remove(e).(sizedTxKey)
+	delete(c.cacheMap, entry.key)
+	c.bytes -= entry.size
+}
@@ -0,0 +1,52 @@
+package cat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestSizedTxCacheByteBudget(t *testing.T) {
+	var (
+		small1 = types.Tx("small1").Key()
+		small2 = types.Tx("small2").Key()
+		small3 = types.Tx("small3").Key()
+		large  = types.Tx("large").Key()
+		huge   = types.Tx("huge").Key()
+	)
+	cache := NewSizedTxCache(100)
+
+	require.True(t, cache.PushSized(small1, 10))
+	require.True(t, cache.PushSized(small2, 10))
+	require.True(t, cache.PushSized(small3, 10))
+	require.False(t, cache.PushSized(small1, 10))
+	require.Equal(t, 30, cache.Bytes())
+
+	// small1 was refreshed so the large tx evicts small2 and small3
+	require.True(t, cache.PushSized(large, 85))
+	require.Equal(t, 95, cache.Bytes())
+	require.Equal(t, 2, cache.Len())
+	require.True(t, cache.Has(small1))
+	require.False(t, cache.Has(small2))
+	require.False(t, cache.Has(small3))
+	require.True(t, cache.Has(large))
+
+	// a tx larger than the entire budget is rejected without evicting
+	require.False(t, cache.PushSized(huge, 101))
+	require.Equal(t, 95, cache.Bytes())
+
+	// a negative size would let the cache exceed its budget
+	require.False(t, cache.PushSized(huge, -50))
+	require.False(t, cache.Has(huge))
+	require.Equal(t, 95, cache.Bytes())
+
+	cache.Remove(large)
+	require.Equal(t, 10, cache.Bytes())
+	require.Equal(t, len(cache.cacheMap), cache.list.Len())
+
+	cache.Reset()
+	require.Zero(t, cache.Bytes())
+	require.Zero(t, cache.Len())
+}
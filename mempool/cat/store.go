@@ -135,19 +135,19 @@ func (s *store) getTxsBelowPriority(priority int64) ([]*wrappedTx, int64) {
 }
 
 // purgeExpiredTxs removes all transactions that are older than the given height
-// and time. Returns the amount of transactions that were removed
-func (s *store) purgeExpiredTxs(expirationHeight int64, expirationAge time.Time) int {
+// and time. Returns the transactions that were removed
+func (s *store) purgeExpiredTxs(expirationHeight int64, expirationAge time.Time) []*wrappedTx {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
-	counter := 0
+	var purged []*wrappedTx
 	for key, tx := range s.txs {
 		if tx.height < expirationHeight || tx.timestamp.Before(expirationAge) {
 			s.bytes -= tx.size()
 			delete(s.txs, key)
-			counter++
+			purged = append(purged, tx)
 		}
 	}
-	return counter
+	return purged
 }
 
 func (s *store) reset() {
@@ -162,7 +162,7 @@ func TestStoreExpiredTxs(t *testing.T) {
 	}
 
 	// half of them should get purged
-	store.purgeExpiredTxs(int64(numTxs/2), time.Time{})
+	require.Len(t, store.purgeExpiredTxs(int64(numTxs/2), time.Time{}), numTxs/2)
 
 	remainingTxs := store.getAllTxs()
 	require.Equal(t, numTxs/2, len(remainingTxs))
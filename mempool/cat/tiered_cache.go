@@ -0,0 +1,125 @@
+package cat
+
+import (
+	tmsync "github.com/cometbft/cometbft/libs/sync"
+	"github.com/cometbft/cometbft/types"
+)
+
+// TieredTxCache is a thread-safe dedup cache split into priority tiers, each an
+// LRU cache with its own capacity. Eviction happens within a tier, so a flood of
+// low priority transactions cannot push out the keys of higher priority ones.
+// Tiers are numbered from 0, the lowest priority.
+type TieredTxCache struct {
+	mtx tmsync.Mutex
+	// tiers are only accessed with mtx held, which also makes moving a key
+	// between tiers atomic
+	tiers []*LRUTxCache
+}
+
+var _ TxCacheBackend = (*TieredTxCache)(nil)
+
+// NewTieredTxCache returns a cache with one tier per capacity, from the lowest
+// to the highest priority. It returns ErrInvalidSize if there are no tiers or a
+// capacity is negative.
+func NewTieredTxCache(capacities ...int) (*TieredTxCache, error) {
+	if len(capacities) == 0 {
+		return nil, ErrInvalidSize
+	}
+	tiers := make([]*LRUTxCache, len(capacities))
+	for i, capacity := range capacities {
+		if capacity < 0 {
+			return nil, ErrInvalidSize
+		}
+		tiers[i] = NewUnsynchronizedLRUTxCache(capacity)
+	}
+	return &TieredTxCache{tiers: tiers}, nil
+}
+
+// PushTiered adds the key to the given tier, which is clamped to the range of
+// tiers. A key already held in a lower tier is promoted to the given one; a key
+// held in the same or a higher tier stays where it is and is marked as recently
+// used. It returns false if the key was already cached.
+func (c *TieredTxCache) PushTiered(txKey types.TxKey, tier int) bool {
+	if tier < 0 {
+		tier = 0
+	}
+	if tier >= len(c.tiers) {
+		tier = len(c.tiers) - 1
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	current := c.tierOf(txKey)
+	switch {
+	case current >= tier:
+		c.tiers[current].Push(txKey)
+		return false
+	case current >= 0:
+		c.tiers[current].Remove(txKey)
+		c.tiers[tier].Push(txKey)
+		return false
+	default:
+		return c.tiers[tier].Push(txKey)
+	}
+}
+
+// Push adds the key to the lowest priority tier.
+func (c *TieredTxCache) Push(txKey types.TxKey) bool {
+	return c.PushTiered(txKey, 0)
+}
+
+// Has reports whether the key is held in any tier.
+func (c *TieredTxCache) Has(txKey types.TxKey) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.tierOf(txKey) >= 0
+}
+
+// Tier returns the tier holding the key, or false if it is not cached.
+func (c *TieredTxCache) Tier(txKey types.TxKey) (int, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	tier := c.tierOf(txKey)
+	return tier, tier >= 0
+}
+
+// Remove removes the key from whichever tier holds it.
+func (c *TieredTxCache) Remove(txKey types.TxKey) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, cache := range c.tiers {
+		cache.Remove(txKey)
+	}
+}
+
+// Reset removes all keys from every tier.
+func (c *TieredTxCache) Reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, cache := range c.tiers {
+		cache.Reset()
+	}
+}
+
+// Len returns the number of keys held across all tiers.
+func (c *TieredTxCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	total := 0
+	for _, cache := range c.tiers {
+		total += cache.Len()
+	}
+	return total
+}
+
+// tierOf returns the tier holding the key, or -1 if no tier does.
+// This assumes that the cache's mutex is already locked.
+func (c *TieredTxCache) tierOf(txKey types.TxKey) int {
+	for i := len(c.tiers) - 1; i >= 0; i-- {
+		if c.tiers[i].Has(txKey) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,68 @@
+package cat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredTxCache(t *testing.T) {
+	_, err := NewTieredTxCache()
+	require.ErrorIs(t, err, ErrInvalidSize)
+	_, err = NewTieredTxCache(2, -1)
+	require.ErrorIs(t, err, ErrInvalidSize)
+
+	keys := testTxKeys(4)
+	cache, err := NewTieredTxCache(2, 2)
+	require.NoError(t, err)
+
+	require.True(t, cache.PushTiered(keys[0], 0))
+	require.False(t, cache.PushTiered(keys[0], 0))
+	tier, ok := cache.Tier(keys[0])
+	require.True(t, ok)
+	require.Zero(t, tier)
+
+	// pushing at a higher tier promotes the key, a lower one leaves it put
+	require.False(t, cache.PushTiered(keys[0], 1))
+	tier, _ = cache.Tier(keys[0])
+	require.Equal(t, 1, tier)
+	require.False(t, cache.Push(keys[0]))
+	tier, _ = cache.Tier(keys[0])
+	require.Equal(t, 1, tier)
+	require.Equal(t, 1, cache.Len())
+
+	// out of range tiers are clamped
+	require.True(t, cache.PushTiered(keys[1], 5))
+	tier, _ = cache.Tier(keys[1])
+	require.Equal(t, 1, tier)
+	require.True(t, cache.PushTiered(keys[2], -1))
+	tier, _ = cache.Tier(keys[2])
+	require.Zero(t, tier)
+
+	cache.Remove(keys[0])
+	require.False(t, cache.Has(keys[0]))
+	_, ok = cache.Tier(keys[0])
+	require.False(t, ok)
+
+	cache.Reset()
+	require.Zero(t, cache.Len())
+	require.False(t, cache.Has(keys[1]))
+}
+
+func TestTieredTxCacheLowTierFlood(t *testing.T) {
+	keys := testTxKeys(102)
+	cache, err := NewTieredTxCache(10, 2)
+	require.NoError(t, err)
+	cache.PushTiered(keys[0], 1)
+	cache.PushTiered(keys[1], 1)
+
+	for _, key := range keys[2:] {
+		cache.PushTiered(key, 0)
+	}
+	require.True(t, cache.Has(keys[0]))
+	require.True(t, cache.Has(keys[1]))
+	// the flood only evicted from its own tier
+	require.Equal(t, 12, cache.Len())
+	require.False(t, cache.Has(keys[2]))
+	require.True(t, cache.Has(keys[101]))
+}
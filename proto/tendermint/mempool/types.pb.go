@@ -5,16 +5,21 @@ package mempool
 
 import (
 	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
 	proto "github.com/gogo/protobuf/proto"
+	_ "github.com/gogo/protobuf/types"
+	github_com_gogo_protobuf_types "github.com/gogo/protobuf/types"
 	io "io"
 	math "math"
 	math_bits "math/bits"
+	time "time"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
+var _ = time.Kitchen
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the proto package it is being compiled against.
@@ -156,7 +161,6 @@ func (m *WantTx) GetTxKey() []byte {
 
 type Message struct {
 	// Types that are valid to be assigned to Sum:
-	//
 	//	*Message_Txs
 	//	*Message_SeenTx
 	//	*Message_WantTx
@@ -253,35 +257,217 @@ func (*Message) XXX_OneofWrappers() []interface{} {
 	}
 }
 
+// SeenTxSetPeer records when a peer last announced a transaction.
+type SeenTxSetPeer struct {
+	Peer uint32    `protobuf:"varint,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	Time time.Time `protobuf:"bytes,2,opt,name=time,proto3,stdtime" json:"time"`
+}
+
+func (m *SeenTxSetPeer) Reset()         { *m = SeenTxSetPeer{} }
+func (m *SeenTxSetPeer) String() string { return proto.CompactTextString(m) }
+func (*SeenTxSetPeer) ProtoMessage()    {}
+func (*SeenTxSetPeer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2af51926fdbcbc05, []int{4}
+}
+func (m *SeenTxSetPeer) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeenTxSetPeer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeenTxSetPeer.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeenTxSetPeer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeenTxSetPeer.Merge(m, src)
+}
+func (m *SeenTxSetPeer) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeenTxSetPeer) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeenTxSetPeer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeenTxSetPeer proto.InternalMessageInfo
+
+func (m *SeenTxSetPeer) GetPeer() uint32 {
+	if m != nil {
+		return m.Peer
+	}
+	return 0
+}
+
+func (m *SeenTxSetPeer) GetTime() time.Time {
+	if m != nil {
+		return m.Time
+	}
+	return time.Time{}
+}
+
+// SeenTxSetEntry lists, in the order they were first seen, the peers that have
+// announced a transaction. first_peer is the peer that announced it first, even
+// if it has since been removed.
+type SeenTxSetEntry struct {
+	TxKey     []byte          `protobuf:"bytes,1,opt,name=tx_key,json=txKey,proto3" json:"tx_key,omitempty"`
+	Time      time.Time       `protobuf:"bytes,2,opt,name=time,proto3,stdtime" json:"time"`
+	Peers     []SeenTxSetPeer `protobuf:"bytes,3,rep,name=peers,proto3" json:"peers"`
+	FirstPeer uint32          `protobuf:"varint,4,opt,name=first_peer,json=firstPeer,proto3" json:"first_peer,omitempty"`
+}
+
+func (m *SeenTxSetEntry) Reset()         { *m = SeenTxSetEntry{} }
+func (m *SeenTxSetEntry) String() string { return proto.CompactTextString(m) }
+func (*SeenTxSetEntry) ProtoMessage()    {}
+func (*SeenTxSetEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2af51926fdbcbc05, []int{5}
+}
+func (m *SeenTxSetEntry) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeenTxSetEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeenTxSetEntry.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeenTxSetEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeenTxSetEntry.Merge(m, src)
+}
+func (m *SeenTxSetEntry) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeenTxSetEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeenTxSetEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeenTxSetEntry proto.InternalMessageInfo
+
+func (m *SeenTxSetEntry) GetTxKey() []byte {
+	if m != nil {
+		return m.TxKey
+	}
+	return nil
+}
+
+func (m *SeenTxSetEntry) GetTime() time.Time {
+	if m != nil {
+		return m.Time
+	}
+	return time.Time{}
+}
+
+func (m *SeenTxSetEntry) GetPeers() []SeenTxSetPeer {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
+func (m *SeenTxSetEntry) GetFirstPeer() uint32 {
+	if m != nil {
+		return m.FirstPeer
+	}
+	return 0
+}
+
+// SeenTxSet is a snapshot of the transactions that peers have announced.
+type SeenTxSet struct {
+	Entries []SeenTxSetEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries"`
+}
+
+func (m *SeenTxSet) Reset()         { *m = SeenTxSet{} }
+func (m *SeenTxSet) String() string { return proto.CompactTextString(m) }
+func (*SeenTxSet) ProtoMessage()    {}
+func (*SeenTxSet) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2af51926fdbcbc05, []int{6}
+}
+func (m *SeenTxSet) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *SeenTxSet) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_SeenTxSet.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *SeenTxSet) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SeenTxSet.Merge(m, src)
+}
+func (m *SeenTxSet) XXX_Size() int {
+	return m.Size()
+}
+func (m *SeenTxSet) XXX_DiscardUnknown() {
+	xxx_messageInfo_SeenTxSet.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SeenTxSet proto.InternalMessageInfo
+
+func (m *SeenTxSet) GetEntries() []SeenTxSetEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Txs)(nil), "tendermint.mempool.Txs")
 	proto.RegisterType((*SeenTx)(nil), "tendermint.mempool.SeenTx")
 	proto.RegisterType((*WantTx)(nil), "tendermint.mempool.WantTx")
 	proto.RegisterType((*Message)(nil), "tendermint.mempool.Message")
+	proto.RegisterType((*SeenTxSetPeer)(nil), "tendermint.mempool.SeenTxSetPeer")
+	proto.RegisterType((*SeenTxSetEntry)(nil), "tendermint.mempool.SeenTxSetEntry")
+	proto.RegisterType((*SeenTxSet)(nil), "tendermint.mempool.SeenTxSet")
 }
 
 func init() { proto.RegisterFile("tendermint/mempool/types.proto", fileDescriptor_2af51926fdbcbc05) }
 
 var fileDescriptor_2af51926fdbcbc05 = []byte{
-	// 274 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0x2b, 0x49, 0xcd, 0x4b,
-	0x49, 0x2d, 0xca, 0xcd, 0xcc, 0x2b, 0xd1, 0xcf, 0x4d, 0xcd, 0x2d, 0xc8, 0xcf, 0xcf, 0xd1, 0x2f,
-	0xa9, 0x2c, 0x48, 0x2d, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0x42, 0xc8, 0xeb, 0x41,
-	0xe5, 0x95, 0xc4, 0xb9, 0x98, 0x43, 0x2a, 0x8a, 0x85, 0x04, 0xb8, 0x98, 0x4b, 0x2a, 0x8a, 0x25,
-	0x18, 0x15, 0x98, 0x35, 0x78, 0x82, 0x40, 0x4c, 0x25, 0x79, 0x2e, 0xb6, 0xe0, 0xd4, 0xd4, 0xbc,
-	0x90, 0x0a, 0x21, 0x51, 0x2e, 0xb6, 0x92, 0x8a, 0xf8, 0xec, 0xd4, 0x4a, 0x09, 0x46, 0x05, 0x46,
-	0x0d, 0x9e, 0x20, 0xd6, 0x92, 0x0a, 0xef, 0xd4, 0x4a, 0x90, 0x82, 0xf0, 0xc4, 0xbc, 0x12, 0xdc,
-	0x0a, 0x56, 0x33, 0x72, 0xb1, 0xfb, 0xa6, 0x16, 0x17, 0x27, 0xa6, 0xa7, 0x0a, 0x69, 0xc3, 0xcc,
-	0x67, 0xd4, 0xe0, 0x36, 0x12, 0xd7, 0xc3, 0x74, 0x88, 0x5e, 0x48, 0x45, 0xb1, 0x07, 0x03, 0xd8,
-	0x6a, 0x21, 0x53, 0x2e, 0xf6, 0xe2, 0xd4, 0xd4, 0xbc, 0xf8, 0x92, 0x0a, 0x09, 0x26, 0xb0, 0x06,
-	0x29, 0x6c, 0x1a, 0x20, 0xae, 0xf3, 0x60, 0x08, 0x62, 0x2b, 0x86, 0xb8, 0xd3, 0x94, 0x8b, 0xbd,
-	0x3c, 0x31, 0xaf, 0x04, 0xa4, 0x8d, 0x19, 0xb7, 0x36, 0x88, 0x9b, 0x41, 0xda, 0xca, 0xc1, 0x2c,
-	0x27, 0x56, 0x2e, 0xe6, 0xe2, 0xd2, 0x5c, 0x27, 0xff, 0x13, 0x8f, 0xe4, 0x18, 0x2f, 0x3c, 0x92,
-	0x63, 0x7c, 0xf0, 0x48, 0x8e, 0x71, 0xc2, 0x63, 0x39, 0x86, 0x0b, 0x8f, 0xe5, 0x18, 0x6e, 0x3c,
-	0x96, 0x63, 0x88, 0x32, 0x4d, 0xcf, 0x2c, 0xc9, 0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x4f,
-	0xce, 0xcf, 0x4d, 0x2d, 0x49, 0x4a, 0x2b, 0x41, 0x30, 0xc0, 0x41, 0xab, 0x8f, 0x19, 0xf2, 0x49,
-	0x6c, 0x60, 0x19, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x66, 0x7b, 0xde, 0x37, 0x96, 0x01,
-	0x00, 0x00,
+	// 442 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x53, 0x4d, 0x8b, 0x13, 0x41,
+	0x10, 0x9d, 0x76, 0xf2, 0xe1, 0x56, 0x76, 0x45, 0x1a, 0x65, 0x43, 0xc0, 0x49, 0x9c, 0x53, 0x40,
+	0x98, 0x81, 0x48, 0xc0, 0x8b, 0x97, 0x01, 0x61, 0x41, 0x64, 0x65, 0x36, 0x20, 0x08, 0x12, 0x92,
+	0xb5, 0x32, 0x0e, 0x6e, 0x77, 0x0f, 0xd3, 0x15, 0x76, 0xf2, 0x2f, 0xf6, 0x7f, 0xf8, 0x23, 0xbc,
+	0xee, 0x71, 0x8f, 0x9e, 0x54, 0x92, 0x3f, 0x22, 0xdd, 0x9d, 0x64, 0x0f, 0x71, 0x3c, 0x78, 0x7b,
+	0x33, 0xf5, 0x5e, 0xd5, 0xab, 0x47, 0x35, 0x04, 0x84, 0xf2, 0x33, 0x96, 0x22, 0x97, 0x14, 0x0b,
+	0x14, 0x85, 0x52, 0x57, 0x31, 0xad, 0x0a, 0xd4, 0x51, 0x51, 0x2a, 0x52, 0x9c, 0xdf, 0xd7, 0xa3,
+	0x6d, 0xbd, 0xf7, 0x24, 0x53, 0x99, 0xb2, 0xe5, 0xd8, 0x20, 0xc7, 0xec, 0xf5, 0x33, 0xa5, 0xb2,
+	0x2b, 0x8c, 0xed, 0xd7, 0x7c, 0xb9, 0x88, 0x29, 0x17, 0xa8, 0x69, 0x26, 0x0a, 0x47, 0x08, 0x4f,
+	0xc1, 0x9f, 0x54, 0x9a, 0x3f, 0x06, 0x9f, 0x2a, 0xdd, 0x65, 0x03, 0x7f, 0x78, 0x9c, 0x1a, 0x18,
+	0xf6, 0xa1, 0x75, 0x81, 0x28, 0x27, 0x15, 0x7f, 0x0a, 0x2d, 0xaa, 0xa6, 0x5f, 0x71, 0xd5, 0x65,
+	0x03, 0x36, 0x3c, 0x4e, 0x9b, 0x54, 0xbd, 0xc5, 0x95, 0x21, 0x7c, 0x98, 0x49, 0xaa, 0x27, 0x7c,
+	0x63, 0xd0, 0x7e, 0x87, 0x5a, 0xcf, 0x32, 0xe4, 0x2f, 0x76, 0xfd, 0xd9, 0xb0, 0x33, 0x3a, 0x8d,
+	0x0e, 0xfd, 0x47, 0x93, 0x4a, 0x9f, 0x79, 0x76, 0x34, 0x1f, 0x43, 0x5b, 0x23, 0xca, 0x29, 0x55,
+	0xdd, 0x07, 0x56, 0xd0, 0xfb, 0x9b, 0xc0, 0xb9, 0x3b, 0xf3, 0xd2, 0x96, 0x76, 0x3e, 0xc7, 0xd0,
+	0xbe, 0x9e, 0x49, 0x32, 0x32, 0xbf, 0x5e, 0xe6, 0x3c, 0x1b, 0xd9, 0xb5, 0x45, 0x49, 0x13, 0x7c,
+	0xbd, 0x14, 0xe1, 0x27, 0x38, 0x71, 0x1d, 0x2f, 0x90, 0xde, 0x23, 0x96, 0x9c, 0x43, 0xa3, 0x40,
+	0x2c, 0xad, 0xe7, 0x93, 0xd4, 0x62, 0xfe, 0x0a, 0x1a, 0x26, 0xc0, 0xbd, 0x2d, 0x97, 0x6e, 0xb4,
+	0x4b, 0x37, 0x9a, 0xec, 0xd2, 0x4d, 0x1e, 0xde, 0xfe, 0xec, 0x7b, 0x37, 0xbf, 0xfa, 0x2c, 0xb5,
+	0x8a, 0xf0, 0x3b, 0x83, 0x47, 0xfb, 0xfe, 0x6f, 0x24, 0x95, 0xab, 0x9a, 0xd8, 0xfe, 0x7f, 0x06,
+	0x7f, 0x0d, 0x4d, 0xe3, 0x52, 0x77, 0xfd, 0x81, 0x3f, 0xec, 0x8c, 0x9e, 0xd7, 0xa7, 0xb6, 0xdd,
+	0x31, 0x69, 0x98, 0x0e, 0xa9, 0x53, 0xf1, 0x67, 0x00, 0x8b, 0xbc, 0xd4, 0x34, 0xb5, 0x6b, 0x37,
+	0xec, 0xda, 0x47, 0xf6, 0x8f, 0xe1, 0x86, 0xe7, 0x70, 0xb4, 0x17, 0xf3, 0x04, 0xda, 0x28, 0xa9,
+	0xcc, 0xd1, 0xdd, 0x4c, 0x67, 0x14, 0xfe, 0x73, 0x98, 0x5d, 0x78, 0x3b, 0x6d, 0x27, 0x4c, 0xce,
+	0x6f, 0xd7, 0x01, 0xbb, 0x5b, 0x07, 0xec, 0xf7, 0x3a, 0x60, 0x37, 0x9b, 0xc0, 0xbb, 0xdb, 0x04,
+	0xde, 0x8f, 0x4d, 0xe0, 0x7d, 0x1c, 0x67, 0x39, 0x7d, 0x59, 0xce, 0xa3, 0x4b, 0x25, 0xe2, 0x4b,
+	0x25, 0x90, 0xe6, 0x0b, 0xba, 0x07, 0xee, 0xc8, 0x0f, 0x9f, 0xc8, 0xbc, 0x65, 0x2b, 0x2f, 0xff,
+	0x0c, 0x00, 0x3d, 0x9d, 0x0c, 0x5e, 0x3f, 0x03, 0x00, 0x00,
 }
 
 func (m *Txs) Marshal() (dAtA []byte, err error) {
@@ -471,6 +657,136 @@ func (m *Message_WantTx) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	}
 	return len(dAtA) - i, nil
 }
+func (m *SeenTxSetPeer) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeenTxSetPeer) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeenTxSetPeer) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	n4, err4 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Time):])
+	if err4 != nil {
+		return 0, err4
+	}
+	i -= n4
+	i = encodeVarintTypes(dAtA, i, uint64(n4))
+	i--
+	dAtA[i] = 0x12
+	if m.Peer != 0 {
+		i = encodeVarintTypes(dAtA, i, uint64(m.Peer))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SeenTxSetEntry) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeenTxSetEntry) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeenTxSetEntry) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.FirstPeer != 0 {
+		i = encodeVarintTypes(dAtA, i, uint64(m.FirstPeer))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Peers) > 0 {
+		for iNdEx := len(m.Peers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Peers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTypes(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	n5, err5 := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_gogo_protobuf_types.SizeOfStdTime(m.Time):])
+	if err5 != nil {
+		return 0, err5
+	}
+	i -= n5
+	i = encodeVarintTypes(dAtA, i, uint64(n5))
+	i--
+	dAtA[i] = 0x12
+	if len(m.TxKey) > 0 {
+		i -= len(m.TxKey)
+		copy(dAtA[i:], m.TxKey)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.TxKey)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SeenTxSet) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SeenTxSet) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SeenTxSet) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for iNdEx := len(m.Entries) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Entries[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTypes(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintTypes(dAtA []byte, offset int, v uint64) int {
 	offset -= sovTypes(v)
 	base := offset
@@ -571,6 +887,58 @@ func (m *Message_WantTx) Size() (n int) {
 	}
 	return n
 }
+func (m *SeenTxSetPeer) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Peer != 0 {
+		n += 1 + sovTypes(uint64(m.Peer))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.Time)
+	n += 1 + l + sovTypes(uint64(l))
+	return n
+}
+
+func (m *SeenTxSetEntry) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.TxKey)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	l = github_com_gogo_protobuf_types.SizeOfStdTime(m.Time)
+	n += 1 + l + sovTypes(uint64(l))
+	if len(m.Peers) > 0 {
+		for _, e := range m.Peers {
+			l = e.Size()
+			n += 1 + l + sovTypes(uint64(l))
+		}
+	}
+	if m.FirstPeer != 0 {
+		n += 1 + sovTypes(uint64(m.FirstPeer))
+	}
+	return n
+}
+
+func (m *SeenTxSet) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Entries) > 0 {
+		for _, e := range m.Entries {
+			l = e.Size()
+			n += 1 + l + sovTypes(uint64(l))
+		}
+	}
+	return n
+}
 
 func sovTypes(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
@@ -983,6 +1351,362 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *SeenTxSetPeer) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTypes
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeenTxSetPeer: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeenTxSetPeer: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Peer", wireType)
+			}
+			m.Peer = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Peer |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.Time, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SeenTxSetEntry) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTypes
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeenTxSetEntry: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeenTxSetEntry: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TxKey", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TxKey = append(m.TxKey[:0], dAtA[iNdEx:postIndex]...)
+			if m.TxKey == nil {
+				m.TxKey = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Time", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(&m.Time, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Peers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Peers = append(m.Peers, SeenTxSetPeer{})
+			if err := m.Peers[len(m.Peers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FirstPeer", wireType)
+			}
+			m.FirstPeer = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.FirstPeer |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SeenTxSet) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTypes
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SeenTxSet: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SeenTxSet: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Entries", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Entries = append(m.Entries, SeenTxSetEntry{})
+			if err := m.Entries[len(m.Entries)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipTypes(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0